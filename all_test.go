@@ -1,8 +1,16 @@
 package zero
 
 import (
-	"github.com/stretchr/testify/assert"
+	"context"
+	"fmt"
+	"runtime"
 	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wdvxdr1123/ZeroBot/message"
 )
 
 func TestType(t *testing.T) {
@@ -58,3 +66,472 @@ func TestMatcher_Delete(t *testing.T) {
 	OnCommand("").Delete()
 	assert.Empty(t, matcherList)
 }
+
+func TestMatcher_CommandInfo(t *testing.T) {
+	m := OnCommandGroup([]string{"echo", "复读"}).
+		SetUsage("echo <内容>").
+		SetDescription("复读消息").
+		SetExamples("/echo hi").
+		SetPermission(SuperUserPermission)
+	defer m.Delete()
+	assert.Equal(t, "echo", m.Command.Name)
+	assert.Equal(t, []string{"复读"}, m.Command.Aliases)
+	assert.Equal(t, []string{"/echo hi"}, m.Command.Examples)
+	assert.Len(t, m.Rules, 2) // 权限同时作为匹配规则
+	assert.False(t, m.Command.Permission(&Event{UserID: 1}, State{}))
+	plain := OnMessage()
+	defer plain.Delete()
+	assert.Nil(t, plain.Command)
+}
+
+func textEvent(text string) *Event {
+	return &Event{PostType: "message", Message: message.Message{message.Text(text)}}
+}
+
+func TestCommandRule(t *testing.T) {
+	BotConfig.CommandPrefixes = []string{"/", "#"}
+	defer func() { BotConfig.CommandPrefixes = nil }()
+
+	rule := CommandRule("music", "musicstop", "a", "ab")
+	for _, c := range []struct {
+		text, command, args string
+	}{
+		{"/music 晴天", "music", "晴天"},
+		{"#musicstop", "musicstop", ""},
+		{"/ab c", "ab", "c"},
+		{"／ＭＵＳＩＣ　晴天", "music", "晴天"},
+	} {
+		state := State{}
+		assert.True(t, rule(textEvent(c.text), state), c.text)
+		assert.Equal(t, c.command, state["command"], c.text)
+		assert.Equal(t, c.args, state["args"], c.text)
+	}
+	assert.False(t, rule(textEvent("/musicplay"), State{}))
+	assert.False(t, rule(textEvent("music"), State{}))
+
+	e := textEvent("music")
+	e.IsToMe = true
+	assert.True(t, rule(e, State{}))
+}
+
+func TestCommandPrefixes(t *testing.T) {
+	assert.Equal(t, Prefixes{""}, CommandPrefixes(0)) // 没有设置前缀时任何消息都可以触发命令
+	assert.True(t, CommandRule("music")(textEvent("music 晴天"), State{}))
+
+	BotConfig.CommandPrefix = "/"
+	defer func() { BotConfig.CommandPrefix = "" }()
+	assert.Equal(t, Prefixes{"/"}, CommandPrefixes(0))
+	assert.False(t, CommandRule("music")(textEvent("music 晴天"), State{}))
+
+	SetGroupPrefixProvider(func(groupID int64) (Prefixes, bool) {
+		return Prefixes{}, groupID == 1
+	})
+	defer SetGroupPrefixProvider(nil)
+	assert.Equal(t, Prefixes{""}, CommandPrefixes(1))
+	assert.Equal(t, Prefixes{"/"}, CommandPrefixes(2))
+}
+
+func TestCommandTree(t *testing.T) {
+	BotConfig.CommandPrefix = "/"
+	defer func() { BotConfig.CommandPrefix = "" }()
+
+	admin := NewCommandTree("admin")
+	root, ban := admin.OnCommand(""), admin.OnCommand("ban")
+	defer root.Delete()
+	defer ban.Delete()
+
+	state := State{}
+	assert.False(t, root.Rules[0](textEvent("/admin  ban 123"), state))
+	assert.True(t, ban.Rules[0](textEvent("/admin  ban 123"), state))
+	assert.Equal(t, "123", state["args"])
+	assert.True(t, root.Rules[0](textEvent("/admin kick"), state))
+	assert.Equal(t, "kick", state["args"])
+
+	// 没有权限使用更长的命令时, 交由较短的命令处理
+	ban.SetPermission(SuperUserPermission)
+	e := textEvent("/admin ban 123")
+	assert.False(t, ban.eligible(e))
+	assert.True(t, root.Rules[0](e, state))
+	assert.Equal(t, "ban 123", state["args"])
+	info, ok := LookupCommand(textEvent("/admin ban 123"))
+	assert.True(t, ok)
+	assert.Equal(t, "admin ban", info.Name)
+}
+
+// tokenHooker 是容量为 tokens 且不会补充的限流 Hooker
+type tokenHooker struct {
+	tokens int
+}
+
+func (h *tokenHooker) Hook() Rule {
+	return func(*Event, State) bool {
+		if h.tokens == 0 {
+			return false
+		}
+		h.tokens--
+		return true
+	}
+}
+
+func TestCommandRule_Hook(t *testing.T) {
+	BotConfig.CommandPrefix = "/"
+	defer func() { BotConfig.CommandPrefix = "" }()
+
+	limiter := &tokenHooker{tokens: 2}
+	AddHook(limiter)
+	called := 0
+	m := OnCommand("ping").Handle(func(*Matcher, Event, State) Response {
+		called++
+		return FinishResponse
+	})
+	_, file, _, _ := runtime.Caller(0)
+	delete(hooks, file)
+	defer m.Delete()
+
+	// 查找最长命令时不执行 Hooker 的 Rule, 每条消息只消耗一个令牌
+	receiveMessage(1, 2, "/ping")
+	receiveMessage(1, 2, "/ping")
+	assert.Equal(t, 2, called)
+	assert.Equal(t, 0, limiter.tokens)
+}
+
+func TestRuleCombinator(t *testing.T) {
+	set := func(k string, ok bool) Rule {
+		return func(_ *Event, state State) bool {
+			state[k] = true
+			return ok
+		}
+	}
+	state := State{}
+	assert.False(t, And(set("a", true), set("b", false))(&Event{}, state))
+	assert.Empty(t, state)
+	assert.True(t, Or(set("a", false), set("b", true), set("c", true))(&Event{}, state))
+	assert.Equal(t, State{"b": true}, state)
+	assert.True(t, Not(set("d", false))(&Event{}, state))
+	assert.Equal(t, State{"b": true}, state)
+
+	assert.Equal(t, "ZeroBot.CommandRule", RuleString(CommandRule("a"))) // 默认不记录内置 Rule 的描述
+	RecordRuleDescriptions(true)
+	defer RecordRuleDescriptions(false)
+	rule := And(OnlyGroup, Or(AdminPermission, Describe("always", set("e", true))), Not(CommandRule("a")))
+	assert.Equal(t, `And(ZeroBot.OnlyGroup, Or(ZeroBot.AdminPermission, always), Not(CommandRule("a")))`, RuleString(rule))
+}
+
+func TestOutboundQueue_Pick(t *testing.T) {
+	BotConfig.Outbound = OutboundConfig{TargetInterval: time.Second}
+	defer func() { BotConfig.Outbound = OutboundConfig{} }()
+
+	q := &outboundQueue{targetNext: map[string]time.Time{}}
+	now := time.Now()
+	normal, reply := &outboundJob{target: "a"}, &outboundJob{target: "a"}
+	other := &outboundJob{target: "b"}
+	q.jobs[1] = []*outboundJob{normal, other}
+	q.jobs[0] = []*outboundJob{reply}
+
+	job, _ := q.pick(now)
+	assert.Equal(t, reply, job) // 回复优先
+	q.sent(job.target, now)
+	job, _ = q.pick(now)
+	assert.Equal(t, other, job) // a 还在冷却, 先发送 b
+	q.sent(job.target, now)
+	job, wait := q.pick(now)
+	assert.Nil(t, job)
+	assert.Equal(t, time.Second, wait)
+	job, _ = q.pick(now.Add(time.Second))
+	assert.Equal(t, normal, job)
+}
+
+func TestOutboundQueue_Do(t *testing.T) {
+	BotConfig.Outbound = OutboundConfig{Interval: time.Millisecond}
+	defer func() { BotConfig.Outbound = OutboundConfig{} }()
+
+	q := &outboundQueue{notify: make(chan struct{}, 1), targetNext: map[string]time.Time{}}
+	block, blocked := make(chan struct{}), make(chan struct{})
+	go q.do("a", false, func() {
+		close(blocked)
+		<-block
+	})
+	<-blocked
+	done := make(chan struct{})
+	go func() {
+		q.do("b", false, func() {})
+		close(done)
+	}()
+	select {
+	case <-done: // 未完成的发送不阻塞之后的消息
+	case <-time.After(time.Second):
+		t.Fatal("outbound queue blocked by a pending send")
+	}
+	close(block)
+}
+
+func TestSendLongMessage(t *testing.T) {
+	BotConfig.LongMessage = LongMessageConfig{Mode: LongMessageSplit, MaxLength: 5}
+	defer func() { BotConfig.LongMessage = LongMessageConfig{} }()
+
+	var sent []interface{}
+	send := func(msg interface{}) (int64, error) {
+		sent = append(sent, msg)
+		return int64(len(sent)), nil
+	}
+	var forwarded message.ForwardMessage
+	forward := func(f message.ForwardMessage) (int64, error) {
+		forwarded = f
+		return 100, nil
+	}
+
+	id, _ := sendLongMessage("hello", send, forward)
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, []interface{}{"hello"}, sent)
+
+	sent = nil
+	id, _ = sendLongMessage("hello world", send, forward)
+	assert.Equal(t, int64(1), id)
+	assert.Len(t, sent, 3)
+
+	BotConfig.LongMessage.Mode = LongMessageForward
+	id, _ = sendLongMessage("hello world", send, forward)
+	assert.Equal(t, int64(100), id)
+	assert.Len(t, forwarded, 3)
+	assert.Equal(t, message.Message{message.Text("hello")}, forwarded[0].Data.Content)
+
+	sent = nil
+	_, _ = sendLongMessage("hello world", send, nil) // 不支持合并转发时拆分发送
+	assert.Len(t, sent, 3)
+}
+
+func TestSendForwardMessage(t *testing.T) {
+	BotConfig.LongMessage = LongMessageConfig{Mode: LongMessageForward, MaxLength: 5}
+	defer func() { BotConfig.LongMessage = LongMessageConfig{} }()
+
+	var actions []string
+	var params []Params
+	callAction = func(action string, p Params) (gjson.Result, error) {
+		actions = append(actions, action)
+		params = append(params, p)
+		return gjson.Parse(`{"message_id":1}`), nil
+	}
+	defer func() { callAction = CallActionWithError }()
+
+	id, err := SendPrivateMessageWithError(2, "hello world")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	_, _ = SendGroupMessageWithError(1, "hello world")
+	_, _ = SendForward(Event{UserID: 2}, message.NewForward().Custom("a", 1, message.Message{message.Text("hi")}).Build())
+	_, _ = SendPrivateMessageWithError(2, "hi") // 未超长时直接发送
+	assert.Equal(t, []string{"send_private_forward_msg", "send_group_forward_msg", "send_private_forward_msg", "send_private_msg"}, actions)
+	assert.Equal(t, int64(2), params[0]["user_id"])
+	assert.Len(t, params[0]["messages"], 3)
+	assert.Equal(t, int64(1), params[1]["group_id"])
+}
+
+// receiveMessage 模拟收到一条群消息
+func receiveMessage(groupID, userID int64, text string) {
+	data := fmt.Sprintf(`{"post_type":"message","message_type":"group","group_id":%d,"user_id":%d,"message":%q,"raw_message":%q,"sender":{"user_id":%d}}`,
+		groupID, userID, text, text, userID)
+	processEvent([]byte(data), gjson.Parse(data))
+}
+
+// waitMatcher 等待其他 goroutine 注册 Matcher
+func waitMatcher() {
+	for {
+		matcherLock.RLock()
+		n := len(matcherList)
+		matcherLock.RUnlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFutureEvent_Next(t *testing.T) {
+	ch, cancel := NewFutureEvent("message", 0, false, CheckUser(2)).NextWithCancel()
+	receiveMessage(1, 3, "other")
+	receiveMessage(1, 2, "hi")
+	e, ok := <-ch
+	assert.True(t, ok)
+	assert.Equal(t, "hi", e.RawMessage)
+	cancel() // 收到事件后取消不会有影响
+	assert.Empty(t, matcherList)
+
+	ch, cancel = NewFutureEvent("message", 0, false).NextWithCancel()
+	cancel()
+	_, ok = <-ch
+	assert.False(t, ok)
+	assert.Empty(t, matcherList)
+
+	BotConfig.Session.Timeout = 10 * time.Millisecond
+	defer func() { BotConfig.Session = SessionConfig{} }()
+	_, ok = <-NewFutureEvent("message", 0, false).Next()
+	assert.False(t, ok) // 超时后关闭 chan
+	assert.Empty(t, matcherList)
+}
+
+func TestFutureEvent_NextContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := NewFutureEvent("message", 0, false).NextContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Empty(t, matcherList)
+
+	done := make(chan Event)
+	go func() {
+		e, _ := NewFutureEvent("message", 0, false).NextContext(context.Background())
+		done <- e
+	}()
+	waitMatcher()
+	receiveMessage(1, 2, "hi")
+	assert.Equal(t, "hi", (<-done).RawMessage)
+}
+
+func TestSessionConfig(t *testing.T) {
+	conf := SessionConfig{}
+	assert.True(t, conf.IsExitWord(textEvent(" 取消 ")))
+	assert.True(t, conf.IsExitWord(textEvent("/cancel")))
+	assert.False(t, conf.IsExitWord(textEvent("取消订阅")))
+	conf.ExitWords = []string{"算了"}
+	assert.True(t, conf.IsExitWord(textEvent("算了")))
+	assert.False(t, conf.IsExitWord(textEvent("取消")))
+
+	rule := SessionRule(&Event{GroupID: 1, UserID: 2})
+	assert.True(t, rule(&Event{GroupID: 1, UserID: 2}, State{}))
+	assert.False(t, rule(&Event{GroupID: 1, UserID: 3}, State{}))
+	assert.False(t, rule(&Event{GroupID: 4, UserID: 2}, State{}))
+}
+
+func TestMatcher_receive(t *testing.T) {
+	m := &Matcher{Event: &Event{GroupID: 1, UserID: 2}}
+	receive := func() (string, error) {
+		ctx, cancel := BotConfig.Session.context()
+		defer cancel()
+		e, err := m.receive(ctx)
+		return e.RawMessage, err
+	}
+
+	type result struct {
+		msg string
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		msg, err := receive()
+		done <- result{msg, err}
+	}()
+	waitMatcher()
+	time.Sleep(20 * time.Millisecond) // 未设置超时时一直等待
+	receiveMessage(1, 3, "其他人")
+	receiveMessage(1, 2, "ZeroBot")
+	assert.Equal(t, result{"ZeroBot", nil}, <-done)
+
+	go func() {
+		msg, err := receive()
+		done <- result{msg, err}
+	}()
+	waitMatcher()
+	receiveMessage(1, 2, "取消")
+	assert.Equal(t, result{"", ErrSessionCanceled}, <-done)
+
+	BotConfig.Session.Timeout = 10 * time.Millisecond
+	defer func() { BotConfig.Session = SessionConfig{} }()
+	_, err := receive()
+	assert.Equal(t, ErrSessionTimeout, err)
+	_, err = m.Receive(0)
+	assert.Equal(t, ErrSessionTimeout, err)
+	assert.Empty(t, matcherList)
+}
+
+func TestMatcher_Reject(t *testing.T) {
+	var counts []int
+	m := OnMessage(func(e *Event, _ State) bool { return e.RawMessage != "跳过" }).
+		SetBlock(true).
+		Handle(func(_ *Matcher, e Event, state State) Response {
+			n, _ := state["n"].(int)
+			state["n"] = n + 1
+			counts = append(counts, n+1)
+			if n+1 < 3 {
+				return RejectResponse
+			}
+			return FinishResponse
+		})
+	defer m.Delete()
+
+	receiveMessage(1, 2, "a")
+	receiveMessage(1, 3, "a")  // 其他用户不在会话中
+	receiveMessage(1, 2, "跳过") // 不满足 Rule, 会话继续
+	receiveMessage(1, 2, "b")
+	receiveMessage(1, 2, "c")
+	assert.Equal(t, []int{1, 1, 2, 3}, counts)
+	assert.Len(t, sessions, 1) // 用户 3 的会话
+	m.Delete()                 // 删除原始 Matcher 时删除其会话
+	assert.Empty(t, matcherList)
+	assert.Empty(t, sessions)
+	StoreMatcher(m)
+
+	receiveMessage(1, 2, "d") // 会话结束后重新触发
+	assert.Equal(t, []int{1, 1, 2, 3, 1}, counts)
+	receiveMessage(1, 2, "取消")
+	assert.Equal(t, []int{1, 1, 2, 3, 1}, counts)
+	assert.Equal(t, []*Matcher{m}, matcherList)
+}
+
+func TestMatcher_Pause(t *testing.T) {
+	var got []string
+	m := OnMessage(func(e *Event, _ State) bool { return e.RawMessage == "开始" }).
+		Handle(func(_ *Matcher, e Event, state State) Response {
+			got = append(got, e.RawMessage)
+			if len(got) == 1 {
+				return PauseResponse
+			}
+			return FinishResponse
+		})
+	defer m.Delete()
+
+	receiveMessage(1, 2, "开始")
+	receiveMessage(1, 2, "开始") // 由会话处理, 不会重新触发原始 Matcher
+	receiveMessage(1, 2, "任意")
+	assert.Equal(t, []string{"开始", "开始"}, got)
+	assert.Equal(t, []*Matcher{m}, matcherList)
+}
+
+func TestMatcher_MaxRetry(t *testing.T) {
+	var counts []int
+	m := OnMessage().SetMaxRetry(1).
+		Handle(func(_ *Matcher, e Event, state State) Response {
+			n, _ := state["n"].(int)
+			state["n"] = n + 1
+			counts = append(counts, n+1)
+			return RejectResponse
+		})
+	defer m.Delete()
+
+	receiveMessage(1, 2, "a")
+	receiveMessage(1, 2, "b")
+	assert.Equal(t, []*Matcher{m}, matcherList) // 达到最大次数后不再等待
+	receiveMessage(1, 2, "c")
+	assert.Equal(t, []int{1, 2, 1}, counts)
+}
+
+func TestMatcher_Timeout(t *testing.T) {
+	var counts []int
+	m := OnMessage().SetTimeout(10 * time.Millisecond).
+		Handle(func(_ *Matcher, e Event, state State) Response {
+			n, _ := state["n"].(int)
+			state["n"] = n + 1
+			counts = append(counts, n+1)
+			if n == 0 {
+				return PauseResponse
+			}
+			return FinishResponse
+		})
+	defer m.Delete()
+
+	receiveMessage(1, 2, "a")
+	time.Sleep(30 * time.Millisecond)
+	matcherLock.RLock()
+	assert.Equal(t, []*Matcher{m}, matcherList)
+	assert.Empty(t, sessions)
+	matcherLock.RUnlock()
+	receiveMessage(1, 2, "b")
+	assert.Equal(t, []int{1, 1}, counts)
+}
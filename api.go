@@ -1,6 +1,9 @@
 package zero
 
 import (
+	"errors"
+	"fmt"
+
 	jsoniter "github.com/json-iterator/go"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
@@ -10,23 +13,56 @@ import (
 
 var json = jsoniter.ConfigFastest
 
+// callAction 发送消息时调用 API, 测试时替换
+var callAction = CallActionWithError
+
+// ErrActionTimeout 等待 API 返回超时, 此时 cqhttp 可能已经执行了该 API
+var ErrActionTimeout = errors.New("timed out")
+
+// APIError 是调用 API 时 cqhttp 返回的错误
+type APIError struct {
+	Action  string
+	RetCode int64
+	Msg     string
+	Wording string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("调用 API: %v 时出现错误, RetCode: %v, Msg: %v, Wording: %v", e.Action, e.RetCode, e.Msg, e.Wording)
+}
+
 // CallAction 调用 cqhttp API
 func CallAction(action string, params Params) gjson.Result {
+	rsp, err := CallActionWithError(action, params)
+	if err != nil {
+		logActionError(err)
+	}
+	return rsp
+}
+
+// CallActionWithError 调用 cqhttp API, cqhttp 返回错误时 error 为 *APIError
+func CallActionWithError(action string, params Params) (gjson.Result, error) {
 	req := webSocketRequest{
 		Action: action,
 		Params: params,
 		Echo:   nextSeq(),
 	}
 	rsp, err := sendAndWait(req)
-	if err == nil {
-		if rsp.RetCode != 0 {
-			log.Errorf("调用 API: %v 时出现错误, RetCode: %v, Msg: %v, Wording: %v", action, rsp.RetCode, rsp.Msg, rsp.Wording)
-			return gjson.Result{}
-		}
-		return rsp.Data
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if rsp.RetCode != 0 {
+		return gjson.Result{}, &APIError{Action: action, RetCode: rsp.RetCode, Msg: rsp.Msg, Wording: rsp.Wording}
+	}
+	return rsp.Data, nil
+}
+
+func logActionError(err error) {
+	if _, ok := err.(*APIError); ok {
+		log.Errorf("%v", err)
+		return
 	}
 	log.Errorf("调用 API: %v 时出现错误", err)
-	return gjson.Result{}
 }
 
 // formatMessage 格式化消息数组
@@ -44,39 +80,97 @@ func formatMessage(msg interface{}) string {
 }
 
 // Send 快捷发送消息
+//
+// 启用 BotConfig.Outbound 时, 使用 Send 回复的消息优先于其他消息发送
 func Send(event Event, message interface{}) int64 {
 	if event.GroupID != 0 {
-		return SendGroupMessage(event.GroupID, message)
+		return sendGroupMessage(event.GroupID, message, true)
 	}
-	return SendPrivateMessage(event.UserID, message)
+	return sendPrivateMessage(event.UserID, message, true)
 }
 
 // SendGroupMessage 发送群消息
 // https://github.com/howmanybots/onebot/blob/master/v11/specs/api/public.md#send_group_msg-%E5%8F%91%E9%80%81%E7%BE%A4%E6%B6%88%E6%81%AF
 func SendGroupMessage(groupID int64, message interface{}) int64 {
-	rsp := CallAction("send_group_msg", Params{ // 调用并保存返回值
-		"group_id": groupID,
-		"message":  message,
-	}).Get("message_id")
-	if rsp.Exists() {
-		log.Infof("发送群消息(%v): %v (id=%v)", groupID, formatMessage(message), rsp.Int())
-		return rsp.Int()
+	return sendGroupMessage(groupID, message, false)
+}
+
+// SendGroupMessageWithError 发送群消息, 并返回发送时出现的错误
+func SendGroupMessageWithError(groupID int64, message interface{}) (int64, error) {
+	return sendGroupMessageWithError(groupID, message, false)
+}
+
+func sendGroupMessage(groupID int64, message interface{}, reply bool) int64 {
+	id, err := sendGroupMessageWithError(groupID, message, reply)
+	if err != nil {
+		logActionError(err)
 	}
-	return 0 // 无法获取返回值
+	return id // 无法获取返回值时为 0
+}
+
+func sendGroupMessageWithError(groupID int64, msg interface{}, reply bool) (int64, error) {
+	return sendLongMessage(msg, func(msg interface{}) (int64, error) {
+		return callSendGroupMessage(groupID, msg, reply)
+	}, func(forward message.ForwardMessage) (int64, error) {
+		return callSendGroupForwardMessage(groupID, forward, reply)
+	})
+}
+
+func callSendGroupMessage(groupID int64, message interface{}, reply bool) (id int64, err error) {
+	outbound.do(groupTarget(groupID), reply, func() {
+		var rsp gjson.Result
+		rsp, err = callAction("send_group_msg", Params{ // 调用并保存返回值
+			"group_id": groupID,
+			"message":  message,
+		})
+		if rsp = rsp.Get("message_id"); rsp.Exists() {
+			log.Infof("发送群消息(%v): %v (id=%v)", groupID, formatMessage(message), rsp.Int())
+			id = rsp.Int()
+		}
+	})
+	return
 }
 
 // SendPrivateMessage 发送私聊消息
 // https://github.com/howmanybots/onebot/blob/master/v11/specs/api/public.md#send_private_msg-%E5%8F%91%E9%80%81%E7%A7%81%E8%81%8A%E6%B6%88%E6%81%AF
 func SendPrivateMessage(userID int64, message interface{}) int64 {
-	rsp := CallAction("send_private_msg", Params{
-		"user_id": userID,
-		"message": message,
-	}).Get("message_id")
-	if rsp.Exists() {
-		log.Infof("发送私聊消息(%v): %v (id=%v)", userID, formatMessage(message), rsp.Int())
-		return rsp.Int()
+	return sendPrivateMessage(userID, message, false)
+}
+
+// SendPrivateMessageWithError 发送私聊消息, 并返回发送时出现的错误
+func SendPrivateMessageWithError(userID int64, message interface{}) (int64, error) {
+	return sendPrivateMessageWithError(userID, message, false)
+}
+
+func sendPrivateMessage(userID int64, message interface{}, reply bool) int64 {
+	id, err := sendPrivateMessageWithError(userID, message, reply)
+	if err != nil {
+		logActionError(err)
 	}
-	return 0 // 无法获取返回值
+	return id // 无法获取返回值时为 0
+}
+
+func sendPrivateMessageWithError(userID int64, msg interface{}, reply bool) (int64, error) {
+	return sendLongMessage(msg, func(msg interface{}) (int64, error) {
+		return callSendPrivateMessage(userID, msg, reply)
+	}, func(forward message.ForwardMessage) (int64, error) {
+		return callSendPrivateForwardMessage(userID, forward, reply)
+	})
+}
+
+func callSendPrivateMessage(userID int64, message interface{}, reply bool) (id int64, err error) {
+	outbound.do(privateTarget(userID), reply, func() {
+		var rsp gjson.Result
+		rsp, err = callAction("send_private_msg", Params{
+			"user_id": userID,
+			"message": message,
+		})
+		if rsp = rsp.Get("message_id"); rsp.Exists() {
+			log.Infof("发送私聊消息(%v): %v (id=%v)", userID, formatMessage(message), rsp.Int())
+			id = rsp.Int()
+		}
+	})
+	return
 }
 
 // DeleteMessage 撤回消息
@@ -339,6 +433,63 @@ func SendGroupForwardMessage(groupID int64, message message.Message) gjson.Resul
 	})
 }
 
+// SendPrivateForwardMessage 发送合并转发(私聊)
+// https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#%E5%8F%91%E9%80%81%E5%90%88%E5%B9%B6%E8%BD%AC%E5%8F%91-%E5%A5%BD%E5%8F%8B
+func SendPrivateForwardMessage(userID int64, message message.Message) gjson.Result {
+	return CallAction("send_private_forward_msg", Params{
+		"user_id":  userID,
+		"messages": message,
+	})
+}
+
+// SendForward 快捷发送合并转发消息, 可以由 message.NewForward 构造
+func SendForward(event Event, forward message.ForwardMessage) (int64, error) {
+	if event.GroupID != 0 {
+		return callSendGroupForwardMessage(event.GroupID, forward, true)
+	}
+	return callSendPrivateForwardMessage(event.UserID, forward, true)
+}
+
+// SendGroupForward 发送合并转发消息到群, 可以由 message.NewForward 构造
+func SendGroupForward(groupID int64, forward message.ForwardMessage) (int64, error) {
+	return callSendGroupForwardMessage(groupID, forward, false)
+}
+
+// SendPrivateForward 发送合并转发消息到私聊, 可以由 message.NewForward 构造
+func SendPrivateForward(userID int64, forward message.ForwardMessage) (int64, error) {
+	return callSendPrivateForwardMessage(userID, forward, false)
+}
+
+func callSendGroupForwardMessage(groupID int64, forward message.ForwardMessage, reply bool) (id int64, err error) {
+	outbound.do(groupTarget(groupID), reply, func() {
+		var rsp gjson.Result
+		rsp, err = callAction("send_group_forward_msg", Params{
+			"group_id": groupID,
+			"messages": forward,
+		})
+		if rsp = rsp.Get("message_id"); rsp.Exists() {
+			log.Infof("发送群合并转发消息(%v): %d 个节点 (id=%v)", groupID, len(forward), rsp.Int())
+			id = rsp.Int()
+		}
+	})
+	return
+}
+
+func callSendPrivateForwardMessage(userID int64, forward message.ForwardMessage, reply bool) (id int64, err error) {
+	outbound.do(privateTarget(userID), reply, func() {
+		var rsp gjson.Result
+		rsp, err = callAction("send_private_forward_msg", Params{
+			"user_id":  userID,
+			"messages": forward,
+		})
+		if rsp = rsp.Get("message_id"); rsp.Exists() {
+			log.Infof("发送私聊合并转发消息(%v): %d 个节点 (id=%v)", userID, len(forward), rsp.Int())
+			id = rsp.Int()
+		}
+	})
+	return
+}
+
 // GetGroupSystemMessage 获取群系统消息
 // https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#%E8%8E%B7%E5%8F%96%E7%BE%A4%E7%B3%BB%E7%BB%9F%E6%B6%88%E6%81%AF
 func GetGroupSystemMessage() gjson.Result {
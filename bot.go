@@ -16,13 +16,47 @@ import (
 
 // Config is config of zero bot
 type Config struct {
-	Host          string   `json:"host"`           //host地址
-	Port          string   `json:"port"`           //端口
-	AccessToken   string   `json:"access_token"`   //认证token
-	NickName      []string `json:"nickname"`       //机器人名称
-	CommandPrefix string   `json:"command_prefix"` //触发命令
-	SuperUsers    []string `json:"super_users"`    //超级用户
-	SelfID        string   `json:"self_id"`        // 机器人账号
+	Host            string            `json:"host"`             //host地址
+	Port            string            `json:"port"`             //端口
+	AccessToken     string            `json:"access_token"`     //认证token
+	NickName        []string          `json:"nickname"`         //机器人名称
+	CommandPrefix   string            `json:"command_prefix"`   //触发命令
+	CommandPrefixes []string          `json:"command_prefixes"` // 多个命令前缀, 设置后忽略 CommandPrefix
+	SuperUsers      []string          `json:"super_users"`      //超级用户
+	SelfID          string            `json:"self_id"`          // 机器人账号
+	Session         SessionConfig     `json:"session"`          // 会话设置
+	Outbound        OutboundConfig    `json:"outbound"`         // 发送消息频率限制
+	LongMessage     LongMessageConfig `json:"long_message"`     // 超长消息的处理方式
+}
+
+// Prefixes 命令前缀
+type Prefixes []string
+
+// groupPrefix 获取群自定义的命令前缀
+var groupPrefix func(groupID int64) (Prefixes, bool)
+
+// SetGroupPrefixProvider 设置获取群自定义命令前缀的函数
+func SetGroupPrefixProvider(provider func(groupID int64) (Prefixes, bool)) {
+	groupPrefix = provider
+}
+
+// CommandPrefixes 返回群中使用的命令前缀, 私聊时 groupID 为 0
+//
+// 没有设置任何前缀时返回空前缀, 即任何消息都可以触发命令
+func CommandPrefixes(groupID int64) Prefixes {
+	prefixes := Prefixes(BotConfig.CommandPrefixes)
+	if len(prefixes) == 0 {
+		prefixes = Prefixes{BotConfig.CommandPrefix}
+	}
+	if groupID != 0 && groupPrefix != nil {
+		if p, ok := groupPrefix(groupID); ok {
+			prefixes = p
+		}
+	}
+	if len(prefixes) == 0 {
+		return Prefixes{""}
+	}
+	return prefixes
 }
 
 // Option
@@ -77,7 +111,7 @@ func sendAndWait(request webSocketRequest) (apiResponse, error) {
 		}
 		return rsp, nil
 	case <-time.After(30 * time.Second):
-		return apiResponse{}, errors.New("timed out")
+		return apiResponse{}, ErrActionTimeout
 	}
 }
 
@@ -128,6 +162,7 @@ func processEvent(response []byte, parsedResponse gjson.Result) {
 		preprocessMessageEvent(&event)
 	}
 
+	var resumed map[*Matcher]bool // 本事件已由会话 Matcher 处理的原始 Matcher
 loop:
 	for _, matcher := range matcherList {
 		if !matcher.Type(&event, nil) {
@@ -135,17 +170,27 @@ loop:
 		}
 		matcherLock.RLock()
 		m := matcher.copy()
+		_, owned := sessions[sessionKey{matcher, event.GroupID, event.UserID}] // 会话中的消息只交给会话 Matcher
 		matcherLock.RUnlock()
+		if owned || resumed[matcher] {
+			continue
+		}
 		for _, rule := range m.Rules {
 			if rule(&event, m.State) == false {
 				continue loop
 			}
 		}
 		m.run(event)
+		if matcher.session {
+			if resumed == nil {
+				resumed = map[*Matcher]bool{}
+			}
+			resumed[matcher.origin] = true
+		}
 		if matcher.Temp {
 			matcher.Delete()
 		}
-		if matcher.Block {
+		if m.Block { // Handler 可以修改本次匹配是否阻断
 			break loop
 		}
 	}
@@ -0,0 +1,44 @@
+package zero
+
+import "strings"
+
+// CommandTree 是命令树的一个节点, 用于注册形如 /admin ban 的子命令
+type CommandTree struct {
+	name  string
+	rules []Rule
+}
+
+// NewCommandTree 创建一个命令树, rules 会作用于该树下的所有命令
+func NewCommandTree(name string, rules ...Rule) *CommandTree {
+	return &CommandTree{name: strings.TrimSpace(name), rules: rules}
+}
+
+// Sub 创建子命令树, 子命令树继承当前树的 rules
+func (t *CommandTree) Sub(name string, rules ...Rule) *CommandTree {
+	return &CommandTree{
+		name:  t.join(name),
+		rules: append(t.rules[:len(t.rules):len(t.rules)], rules...),
+	}
+}
+
+// OnCommand 注册当前树下的子命令, name 为空时注册当前树本身的命令
+func (t *CommandTree) OnCommand(name string, rules ...Rule) *Matcher {
+	return OnCommand(t.join(name), append(t.rules[:len(t.rules):len(t.rules)], rules...)...)
+}
+
+// OnCommandGroup 注册当前树下的子命令组
+func (t *CommandTree) OnCommandGroup(names []string, rules ...Rule) *Matcher {
+	commands := make([]string, len(names))
+	for i, name := range names {
+		commands[i] = t.join(name)
+	}
+	return OnCommandGroup(commands, append(t.rules[:len(t.rules):len(t.rules)], rules...)...)
+}
+
+func (t *CommandTree) join(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return t.name
+	}
+	return t.name + " " + name
+}
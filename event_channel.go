@@ -1,5 +1,11 @@
 package zero
 
+import (
+	"context"
+	"sync"
+	"time"
+)
+
 // FutureEvent 是 ZeroBot 交互式的核心，用于异步获取指定事件
 type FutureEvent struct {
 	Type     string
@@ -30,21 +36,51 @@ func (m *Matcher) FutureEvent(Type string, rule ...Rule) *FutureEvent {
 
 // Next 返回一个 chan 用于接收下一个指定事件
 //
-// 该 chan 必须接收，如需手动取消监听，请使用 Repeat 方法
+// 设置了 BotConfig.Session.Timeout 时, 超时后监听会被删除并关闭 chan;
+// 未设置时监听会一直保留, 如需手动取消监听, 请使用 NextWithCancel 或 NextContext
 func (n *FutureEvent) Next() <-chan Event {
-	ch := make(chan Event)
-	StoreTempMatcher(&Matcher{
+	ch, cancel := n.NextWithCancel()
+	if timeout := BotConfig.Session.Timeout; timeout > 0 {
+		time.AfterFunc(timeout, cancel)
+	}
+	return ch
+}
+
+// NextWithCancel 返回一个 chan 用于接收下一个指定事件, 和一个取消监听的函数
+//
+// 取消监听后 chan 会被关闭, 收到事件后再取消不会有影响
+func (n *FutureEvent) NextWithCancel() (recv <-chan Event, cancel func()) {
+	ch := make(chan Event, 1)
+	var once sync.Once
+	matcher := StoreTempMatcher(&Matcher{
 		Type:     Type(n.Type),
 		Block:    n.Block,
 		Priority: n.Priority,
 		Rules:    n.Rule,
 		Handler: func(_ *Matcher, e Event, _ State) Response {
-			ch <- e
-			close(ch)
+			once.Do(func() { // 临时 Matcher 被删除前可能匹配到多个事件, 只接收第一个
+				ch <- e
+				close(ch)
+			})
 			return FinishResponse
 		},
 	})
-	return ch
+	return ch, func() {
+		matcher.Delete()
+		once.Do(func() { close(ch) })
+	}
+}
+
+// NextContext 等待下一个指定事件, ctx 被取消时停止监听并返回 ctx.Err()
+func (n *FutureEvent) NextContext(ctx context.Context) (Event, error) {
+	ch, cancel := n.NextWithCancel()
+	select {
+	case e := <-ch:
+		return e, nil
+	case <-ctx.Done():
+		cancel()
+		return Event{}, ctx.Err()
+	}
 }
 
 // Repeat 返回一个 chan 用于接收无穷个指定事件，和一个取消监听的函数
@@ -7,6 +7,8 @@ import (
 	_ "github.com/wdvxdr1123/ZeroBot/example/music"
 	_ "github.com/wdvxdr1123/ZeroBot/example/priority"
 	_ "github.com/wdvxdr1123/ZeroBot/example/repeat"
+	"github.com/wdvxdr1123/ZeroBot/extension/help"
+	_ "github.com/wdvxdr1123/ZeroBot/extension/reminder"
 )
 
 func init() {
@@ -18,13 +20,14 @@ func init() {
 }
 
 func main() {
+	help.Register(&help.Options{ForwardThreshold: 20})
 	zero.Run(zero.Config{
-		Host:          "127.0.0.1",
-		Port:          "6700",
-		AccessToken:   "",
-		NickName:      []string{"bot"},
-		CommandPrefix: "/",
-		SuperUsers:    []string{"123456"},
+		Host:            "127.0.0.1",
+		Port:            "6700",
+		AccessToken:     "",
+		NickName:        []string{"bot"},
+		CommandPrefixes: []string{"/", "#"},
+		SuperUsers:      []string{"123456"},
 	})
 	select {}
 }
@@ -6,20 +6,27 @@ import (
 
 	zero "github.com/wdvxdr1123/ZeroBot"
 	"github.com/wdvxdr1123/ZeroBot/extension"
+	"github.com/wdvxdr1123/ZeroBot/extension/dialog"
 	"github.com/wdvxdr1123/ZeroBot/extension/rate"
 	"github.com/wdvxdr1123/ZeroBot/message"
 )
 
-var limit = rate.NewManager(time.Minute*1, 1)
+var limit = rate.NewLimit(rate.Options{
+	Interval: time.Minute * 1,
+	Scope:    rate.ScopeUser,
+	Reply:    "您的请求太快，请稍后重试0x0...",
+})
+
+var ask = dialog.New(dialog.Step{Key: "args", Prompt: "请输入要点的歌曲!"})
 
 var _ = zero.OnCommandGroup([]string{"music", "点歌"}).
 	SetBlock(true).
 	SetPriority(8).
+	SetUsage("music <歌曲名>").
+	SetDescription("点一首网易云音乐").
+	SetExamples("/music 晴天").
+	Use(limit.Middleware()).
 	Handle(func(matcher *Matcher, event Event, state State) Response {
-		if limit.Load(event.UserID).Acquire() == false {
-			zero.Send(event, "您的请求太快，请稍后重试0x0...")
-			return zero.FinishResponse
-		}
 		var cmd extension.CommandModel
 		err := state.Parse(&cmd)
 		if err != nil {
@@ -27,18 +34,11 @@ var _ = zero.OnCommandGroup([]string{"music", "点歌"}).
 		}
 
 		if cmd.Args == "" { // 未填写歌曲名,索取歌曲名
-			zero.Send(event, message.Message{message.Text("请输入要点的歌曲!")})
-			next := matcher.FutureEvent("message", zero.CheckUser(event.UserID))
-			recv, cancel := next.Repeat()
-			for e := range recv {
-				msg := e.Message.ExtractPlainText()
-				if msg != "" {
-					cmd.Args = msg
-					cancel()
-					continue
-				}
-				zero.Send(event, "歌曲名不合法oxo")
+			err = ask.Run(matcher, state)
+			if err != nil {
+				return zero.FinishResponse
 			}
+			cmd.Args = state["args"].(string)
 		}
 
 		zero.Send(event, message.Music("163", QueryNeteaseMusic(cmd.Args)))
@@ -0,0 +1,235 @@
+// Package broadcast provides a helper to send a message to many groups or friends.
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension"
+)
+
+// Target is a group or a friend to send to.
+type Target struct {
+	GroupID int64 // 群号, 为 0 时发送私聊消息
+	UserID  int64 // 好友账号
+}
+
+func (t Target) String() string {
+	if t.GroupID != 0 {
+		return fmt.Sprintf("群(%d)", t.GroupID)
+	}
+	return fmt.Sprintf("好友(%d)", t.UserID)
+}
+
+// Result is the result of sending to a target.
+type Result struct {
+	Target
+	MessageID int64 // 发送成功时的消息 ID
+	Attempts  int   // 尝试发送的次数
+	Err       error // 发送失败的原因
+}
+
+// Options holds the optional parameters of a broadcast.
+type Options struct {
+	// Interval 两个目标之间的发送间隔, 为 0 时为 1 秒, 小于 0 时不等待
+	Interval time.Duration
+	// Retry 失败后的最大重试次数, 为 0 时为 2, 小于 0 时不重试
+	Retry int
+	// RetryInterval 重试前等待的时间, 为 0 时为 3 秒, 小于 0 时不等待
+	RetryInterval time.Duration
+	// Retryable 判断错误是否可以重试, 默认只重试连接断开等非 cqhttp 返回的错误,
+	// 不重试超时, 因为超时的消息可能已经发送
+	Retryable func(err error) bool
+	// Progress 每个目标发送完成后调用
+	Progress func(done, total int, result Result)
+}
+
+// 发送消息, 测试时替换
+var (
+	sendGroup   = zero.SendGroupMessageWithError
+	sendPrivate = zero.SendPrivateMessageWithError
+)
+
+// Groups returns all groups accepted by filter, nil filter accepts all.
+func Groups(filter func(group zero.Group) bool) []Target {
+	var targets []Target
+	zero.GetGroupList().ForEach(func(_, value gjson.Result) bool {
+		group := zero.Group{
+			ID:             value.Get("group_id").Int(),
+			Name:           value.Get("group_name").String(),
+			MemberCount:    value.Get("member_count").Int(),
+			MaxMemberCount: value.Get("max_member_count").Int(),
+		}
+		if filter == nil || filter(group) {
+			targets = append(targets, Target{GroupID: group.ID})
+		}
+		return true
+	})
+	return targets
+}
+
+// Friends returns all friends accepted by filter, nil filter accepts all.
+func Friends(filter func(userID int64, nickname string) bool) []Target {
+	var targets []Target
+	zero.GetFriendList().ForEach(func(_, value gjson.Result) bool {
+		id := value.Get("user_id").Int()
+		if filter == nil || filter(id, value.Get("nickname").String()) {
+			targets = append(targets, Target{UserID: id})
+		}
+		return true
+	})
+	return targets
+}
+
+// Send sends the message to targets one by one, stops when ctx is done,
+// and returns the result of each target.
+func Send(ctx context.Context, targets []Target, message interface{}, o *Options) []Result {
+	opt := options(o)
+	results := make([]Result, len(targets))
+	for i, target := range targets {
+		results[i].Target = target
+		if i > 0 && sleep(ctx, opt.Interval) != nil {
+			for j := i; j < len(targets); j++ {
+				results[j] = Result{Target: targets[j], Err: ctx.Err()}
+			}
+			break
+		}
+		r := &results[i]
+		for r.Attempts = 1; ; r.Attempts++ {
+			if target.GroupID != 0 {
+				r.MessageID, r.Err = sendGroup(target.GroupID, message)
+			} else {
+				r.MessageID, r.Err = sendPrivate(target.UserID, message)
+			}
+			if r.Err == nil || r.Attempts > opt.Retry || !opt.Retryable(r.Err) {
+				break
+			}
+			if err := sleep(ctx, opt.RetryInterval); err != nil {
+				r.Err = err
+				break
+			}
+		}
+		if opt.Progress != nil {
+			opt.Progress(i+1, len(targets), *r)
+		}
+	}
+	return results
+}
+
+// Report returns a readable summary of the results.
+func Report(results []Result) string {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", r.Target, r.Err))
+		}
+	}
+	report := fmt.Sprintf("广播完成, 成功 %d 个, 失败 %d 个", len(results)-len(failed), len(failed))
+	if len(failed) > 0 {
+		report += "\n" + strings.Join(failed, "\n")
+	}
+	return report
+}
+
+// RegisterCommand registers a command for super users to broadcast to all groups
+// in background, and a "<command> cancel" command to stop the running broadcast.
+func RegisterCommand(command string, o *Options) (start, cancel *zero.Matcher) {
+	var (
+		mu   sync.Mutex
+		stop context.CancelFunc // 正在进行的广播
+	)
+	start = zero.OnCommand(command).
+		SetBlock(true).
+		SetPermission(zero.SuperUserPermission).
+		SetUsage(command + " <消息>").
+		SetDescription("向所有群广播消息").
+		Handle(func(_ *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+			var cmd extension.CommandModel
+			_ = state.Parse(&cmd)
+			if strings.TrimSpace(cmd.Args) == "" {
+				zero.Send(event, "请输入要广播的消息")
+				return zero.FinishResponse
+			}
+			mu.Lock()
+			if stop != nil {
+				mu.Unlock()
+				zero.Send(event, "已有广播正在进行")
+				return zero.FinishResponse
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			stop = cancel
+			mu.Unlock()
+			go func() {
+				defer func() {
+					mu.Lock()
+					stop = nil
+					mu.Unlock()
+					cancel()
+				}()
+				targets := Groups(nil)
+				zero.Send(event, fmt.Sprintf("开始向 %d 个群广播", len(targets)))
+				zero.Send(event, Report(Send(ctx, targets, cmd.Args, o)))
+			}()
+			return zero.FinishResponse
+		})
+	cancel = zero.OnCommand(command + " cancel").
+		SetBlock(true).
+		SetPermission(zero.SuperUserPermission).
+		SetDescription("取消正在进行的广播").
+		Handle(func(_ *zero.Matcher, event zero.Event, _ zero.State) zero.Response {
+			mu.Lock()
+			running := stop != nil
+			if running {
+				stop()
+			}
+			mu.Unlock()
+			if !running {
+				zero.Send(event, "没有正在进行的广播")
+			}
+			return zero.FinishResponse
+		})
+	return start, cancel
+}
+
+func options(o *Options) Options {
+	var opt Options
+	if o != nil {
+		opt = *o
+	}
+	if opt.Interval == 0 {
+		opt.Interval = time.Second
+	}
+	if opt.Retry == 0 {
+		opt.Retry = 2
+	}
+	if opt.RetryInterval == 0 {
+		opt.RetryInterval = 3 * time.Second
+	}
+	if opt.Retryable == nil {
+		opt.Retryable = retryable
+	}
+	return opt
+}
+
+func retryable(err error) bool {
+	if _, ok := err.(*zero.APIError); ok {
+		return false
+	}
+	return err != zero.ErrActionTimeout && err != context.Canceled && err != context.DeadlineExceeded
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
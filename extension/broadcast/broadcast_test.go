@@ -0,0 +1,72 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+func TestOptions(t *testing.T) {
+	opt := options(nil)
+	assert.Equal(t, time.Second, opt.Interval)
+	assert.Equal(t, 2, opt.Retry)
+	assert.Equal(t, 3*time.Second, opt.RetryInterval)
+	assert.True(t, opt.Retryable(errors.New("channel closed")))
+	assert.False(t, opt.Retryable(zero.ErrActionTimeout)) // 超时的消息可能已经发送
+	assert.False(t, opt.Retryable(&zero.APIError{RetCode: 100}))
+
+	opt = options(&Options{Interval: -1, Retry: -1, RetryInterval: time.Minute})
+	assert.Equal(t, time.Duration(-1), opt.Interval)
+	assert.Equal(t, -1, opt.Retry) // 小于 0 时不重试
+	assert.Equal(t, time.Minute, opt.RetryInterval)
+}
+
+func TestSend(t *testing.T) {
+	attempts := map[int64]int{}
+	sendGroup = func(groupID int64, _ interface{}) (int64, error) {
+		attempts[groupID]++
+		switch {
+		case groupID == 2 && attempts[groupID] < 2:
+			return 0, errors.New("channel closed")
+		case groupID == 3:
+			return 0, zero.ErrActionTimeout
+		}
+		return groupID * 10, nil
+	}
+	defer func() { sendGroup = zero.SendGroupMessageWithError }()
+
+	var progress []int
+	targets := []Target{{GroupID: 1}, {GroupID: 2}, {GroupID: 3}}
+	results := Send(context.Background(), targets, "hi", &Options{
+		Interval:      -1,
+		RetryInterval: -1,
+		Progress:      func(done, _ int, _ Result) { progress = append(progress, done) },
+	})
+	assert.Equal(t, []Result{
+		{Target: Target{GroupID: 1}, MessageID: 10, Attempts: 1},
+		{Target: Target{GroupID: 2}, MessageID: 20, Attempts: 2},
+		{Target: Target{GroupID: 3}, Attempts: 1, Err: zero.ErrActionTimeout},
+	}, results)
+	assert.Equal(t, []int{1, 2, 3}, progress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = Send(ctx, targets, "hi", &Options{Interval: time.Hour})
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, context.Canceled, results[1].Err)
+	assert.Equal(t, context.Canceled, results[2].Err)
+}
+
+func TestReport(t *testing.T) {
+	assert.Equal(t, "广播完成, 成功 1 个, 失败 0 个", Report([]Result{{Target: Target{GroupID: 1}}}))
+	assert.Equal(t, "广播完成, 成功 1 个, 失败 2 个\n群(2): timed out\n好友(3): context canceled", Report([]Result{
+		{Target: Target{GroupID: 1}},
+		{Target: Target{GroupID: 2}, Err: zero.ErrActionTimeout},
+		{Target: Target{UserID: 3}, Err: context.Canceled},
+	}))
+}
@@ -0,0 +1,199 @@
+// Package dialog provides declarative multi-step dialogs, the
+// framework asks the user step by step through zero.FutureEvent.
+package dialog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+// ErrTooManyRetries 用户多次回复无效内容
+var ErrTooManyRetries = errors.New("too many retries")
+
+// 发送和接收消息, 测试时替换
+var (
+	send    = zero.Send
+	receive = (*zero.Matcher).Receive
+)
+
+// Parser 解析并校验用户的回复, 返回的 error 会作为提示发送给用户
+type Parser func(event zero.Event) (interface{}, error)
+
+// Step 是对话中的一步
+type Step struct {
+	// Key 回复保存到 State 中的键, State 中已有非空值时跳过该步
+	Key string
+	// Prompt 提示消息
+	Prompt interface{}
+	// Parser 解析回复, 为 nil 时使用 Text
+	Parser Parser
+	// Retry 回复无效时最多重试的次数, 为 0 时为 3 次
+	Retry int
+	// Optional 为 true 时用户可以回复 Dialog.SkipWord 跳过该步
+	Optional bool
+}
+
+// Dialog 是一个多步对话
+type Dialog struct {
+	// Steps 对话的步骤
+	Steps []Step
+	// Timeout 每一步等待回复的时间, 为 0 时使用 zero.BotConfig.Session.Timeout
+	Timeout time.Duration
+	// SkipWord 跳过可选步骤的关键词, 默认为 跳过
+	SkipWord string
+}
+
+// New returns a dialog with steps.
+func New(steps ...Step) *Dialog {
+	return &Dialog{Steps: steps}
+}
+
+// Step appends a step to the dialog.
+func (d *Dialog) Step(step Step) *Dialog {
+	d.Steps = append(d.Steps, step)
+	return d
+}
+
+// Run 依次询问触发 matcher 的用户, 并将回复保存到 state 中
+//
+// 会话超时或被取消时返回 zero.ErrSessionTimeout 或 zero.ErrSessionCanceled
+func (d *Dialog) Run(matcher *zero.Matcher, state zero.State) error {
+	skipWord := d.SkipWord
+	if skipWord == "" {
+		skipWord = "跳过"
+	}
+	for _, step := range d.Steps {
+		if v, ok := state[step.Key]; ok && v != "" && v != nil {
+			continue
+		}
+		parser, retry := step.Parser, step.Retry
+		if parser == nil {
+			parser = Text()
+		}
+		if retry <= 0 {
+			retry = 3
+		}
+		prompt := step.Prompt
+		if step.Optional {
+			prompt = fmt.Sprint(prompt, " (回复 ", skipWord, " 跳过)")
+		}
+		send(*matcher.Event, prompt)
+		for i := 0; ; i++ {
+			e, err := receive(matcher, d.Timeout)
+			if err != nil {
+				return err
+			}
+			if step.Optional && strings.TrimSpace(e.Message.ExtractPlainText()) == skipWord {
+				break
+			}
+			v, err := parser(e)
+			if err == nil {
+				state[step.Key] = v
+				break
+			}
+			if i >= retry {
+				send(*matcher.Event, err.Error()+", 已超过重试次数")
+				return ErrTooManyRetries
+			}
+			send(*matcher.Event, err.Error()+", 请重新输入")
+		}
+	}
+	return nil
+}
+
+// Text 返回去除首尾空白后非空的纯文本
+func Text() Parser {
+	return func(event zero.Event) (interface{}, error) {
+		text := strings.TrimSpace(event.Message.ExtractPlainText())
+		if text == "" {
+			return nil, errors.New("内容不能为空")
+		}
+		return text, nil
+	}
+}
+
+// Int 返回 [min, max] 范围内的整数
+func Int(min, max int) Parser {
+	return func(event zero.Event) (interface{}, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(event.Message.ExtractPlainText()))
+		if err != nil {
+			return nil, errors.New("请输入一个整数")
+		}
+		if n < min || n > max {
+			return nil, fmt.Errorf("请输入 %d 到 %d 之间的整数", min, max)
+		}
+		return n, nil
+	}
+}
+
+// Choice 返回 options 中的一项
+func Choice(options ...string) Parser {
+	return func(event zero.Event) (interface{}, error) {
+		text := strings.TrimSpace(event.Message.ExtractPlainText())
+		for _, option := range options {
+			if text == option {
+				return option, nil
+			}
+		}
+		return nil, errors.New("请从 " + strings.Join(options, ", ") + " 中选择")
+	}
+}
+
+// Decode 将 state 中的回复按 zero 标签保存到 model 指向的结构体,
+// 与 zero.State.Parse 不同, 类型不匹配的回复将返回错误
+func Decode(state zero.State, model interface{}) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("dialog: model must be a pointer to struct")
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, ok := v.Type().Field(i).Tag.Lookup("zero")
+		if !ok || state[key] == nil {
+			continue
+		}
+		val, field := reflect.ValueOf(state[key]), v.Field(i)
+		switch {
+		case val.Type().AssignableTo(field.Type()):
+			field.Set(val)
+		case isNumber(val.Kind()) && isNumber(field.Kind()):
+			n, ok := convertNumber(val, field.Type())
+			if !ok {
+				return fmt.Errorf("dialog: cannot decode %v into field %v of type %v without loss", val.Interface(), v.Type().Field(i).Name, field.Type())
+			}
+			field.Set(n)
+		default:
+			return fmt.Errorf("dialog: cannot decode %v into field %v of type %v", val.Type(), v.Type().Field(i).Name, field.Type())
+		}
+	}
+	return nil
+}
+
+// convertNumber 转换数字类型, 截断小数、溢出或改变符号时返回 false
+func convertNumber(val reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	n := val.Convert(t)
+	if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+		return n, true
+	}
+	return n, negative(n) == negative(val) && n.Convert(val.Type()).Interface() == val.Interface()
+}
+
+func negative(v reflect.Value) bool {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return v.Int() < 0
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float() < 0
+	}
+	return false
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
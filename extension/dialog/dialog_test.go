@@ -0,0 +1,76 @@
+package dialog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+func TestDecode(t *testing.T) {
+	var model struct {
+		Name  string  `zero:"name"`
+		Age   int64   `zero:"age"`
+		Note  string  `zero:"note"`
+		Level uint8   `zero:"level"`
+		Score float32 `zero:"score"`
+	}
+	assert.NoError(t, Decode(zero.State{"name": "zero", "age": 3, "level": 2.0, "score": 9.5}, &model))
+	assert.Equal(t, "zero", model.Name)
+	assert.Equal(t, int64(3), model.Age)
+	assert.Equal(t, uint8(2), model.Level)
+	assert.Equal(t, float32(9.5), model.Score)
+	assert.Error(t, Decode(zero.State{"note": 1}, &model))
+	assert.Error(t, Decode(zero.State{"age": 3.5}, &model)) // 不截断小数
+	assert.Error(t, Decode(zero.State{"level": 256}, &model))
+	assert.Error(t, Decode(zero.State{"level": -1}, &model))
+	assert.Error(t, Decode(zero.State{}, model))
+}
+
+// mockSession 替换收发消息, 依次返回 replies 中的回复, 回复用完后返回 zero.ErrSessionTimeout
+func mockSession(t *testing.T, replies ...string) *[]interface{} {
+	var sent []interface{}
+	send = func(_ zero.Event, msg interface{}) int64 {
+		sent = append(sent, msg)
+		return 0
+	}
+	receive = func(_ *zero.Matcher, _ time.Duration) (zero.Event, error) {
+		if len(replies) == 0 {
+			return zero.Event{}, zero.ErrSessionTimeout
+		}
+		reply := replies[0]
+		replies = replies[1:]
+		return zero.Event{Message: message.Message{message.Text(reply)}}, nil
+	}
+	t.Cleanup(func() {
+		send, receive = zero.Send, (*zero.Matcher).Receive
+	})
+	return &sent
+}
+
+func TestDialog_Run(t *testing.T) {
+	d := New(
+		Step{Key: "name", Prompt: "名字?"},
+		Step{Key: "age", Prompt: "年龄?", Parser: Int(1, 150), Retry: 1},
+		Step{Key: "note", Prompt: "备注?", Optional: true},
+	)
+	matcher := &zero.Matcher{Event: &zero.Event{}}
+
+	sent := mockSession(t, "zero", "abc", "3", "跳过")
+	state := zero.State{}
+	assert.NoError(t, d.Run(matcher, state))
+	assert.Equal(t, zero.State{"name": "zero", "age": 3}, state)
+	assert.Equal(t, []interface{}{"名字?", "年龄?", "请输入一个整数, 请重新输入", "备注? (回复 跳过 跳过)"}, *sent)
+
+	sent = mockSession(t, "abc", "200")
+	state = zero.State{"name": "zero"} // 已有的回复不再询问
+	assert.Equal(t, ErrTooManyRetries, d.Run(matcher, state))
+	assert.Equal(t, []interface{}{"年龄?", "请输入一个整数, 请重新输入", "请输入 1 到 150 之间的整数, 已超过重试次数"}, *sent)
+
+	mockSession(t, "zero")
+	state = zero.State{}
+	assert.Equal(t, zero.ErrSessionTimeout, d.Run(matcher, state))
+	assert.Equal(t, zero.State{"name": "zero"}, state)
+}
@@ -0,0 +1,231 @@
+// Package help provides a help command generated from the registered commands.
+package help
+
+import (
+	"strconv"
+	"strings"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// Options holds the optional parameters for the help command.
+type Options struct {
+	// Command 帮助命令名, 默认为 help
+	Command string
+	// Priority 帮助命令优先级
+	Priority int
+	// ForwardThreshold 群聊中帮助超过该行数时以合并转发发送, 为 0 时不使用合并转发
+	ForwardThreshold int
+}
+
+// section 一个插件的帮助
+type section struct {
+	title    string
+	names    []string
+	commands []*zero.CommandInfo
+}
+
+// Register registers the help command to ZeroBot.
+func Register(o *Options) *zero.Matcher {
+	var opt Options
+	if o != nil {
+		opt = *o
+	}
+	if opt.Command == "" {
+		opt.Command = "help"
+	}
+	return zero.OnCommand(opt.Command).
+		SetBlock(true).
+		SetPriority(opt.Priority).
+		SetUsage(opt.Command + " [命令|插件]").
+		SetDescription("显示命令帮助").
+		Handle(func(_ *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+			var cmd extension.CommandModel
+			_ = state.Parse(&cmd)
+			sections := collect(&event)
+			prefixes := zero.CommandPrefixes(event.GroupID)
+			prefix := ""
+			if len(prefixes) > 0 {
+				prefix = prefixes[0]
+			}
+			name := strings.TrimSpace(cmd.Args)
+			if name == "" {
+				send(event, sections, prefix, opt.ForwardThreshold)
+				return zero.FinishResponse
+			}
+			for _, p := range prefixes {
+				if p != "" && strings.HasPrefix(name, p) {
+					name = name[len(p):]
+					break
+				}
+			}
+			for _, s := range sections {
+				for _, c := range s.commands {
+					if c.Name == name || contains(c.Aliases, name) {
+						zero.Send(event, detail(c, prefix))
+						return zero.FinishResponse
+					}
+				}
+			}
+			for _, s := range sections {
+				if contains(s.names, name) {
+					send(event, []section{s}, prefix, opt.ForwardThreshold)
+					return zero.FinishResponse
+				}
+			}
+			zero.Send(event, "没有找到命令或插件: "+name)
+			return zero.FinishResponse
+		})
+}
+
+// collect 收集当前事件可以使用的命令, 按插件分组
+func collect(event *zero.Event) []section {
+	var (
+		sections []section
+		index    = map[string]int{}
+	)
+	zero.ForEachPlugin(func(info zero.PluginInfo) bool {
+		if _, ok := index[info.PluginName]; !ok {
+			index[info.PluginName] = len(sections)
+			sections = append(sections, section{title: title(info), names: []string{info.PluginName}})
+		}
+		return true
+	})
+	var others section
+	others.title = "其他命令"
+	zero.ForEachMatcher(func(m *zero.Matcher) bool {
+		c := m.Command
+		if c == nil || c.Name == "" || !available(m, event) {
+			return true
+		}
+		if info, ok := m.Plugin(); ok {
+			if i, ok := index[info.PluginName]; ok {
+				sections[i].commands = append(sections[i].commands, c)
+				return true
+			}
+		}
+		others.commands = append(others.commands, c)
+		return true
+	})
+	var ret []section
+	for _, s := range append(sections, others) {
+		if len(s.commands) > 0 {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// available 检查命令的权限以及插件在群内是否被禁用
+func available(m *zero.Matcher, event *zero.Event) bool {
+	if m.Command.Permission != nil && !m.Command.Permission(event, zero.State{}) {
+		return false
+	}
+	if event.GroupID == 0 {
+		return true
+	}
+	return enabled(m.Hooker(), event.GroupID)
+}
+
+// enabled 检查 Hooker 是否允许该群使用, 没有实现 IsEnabled 的 manager.Manager 视为启用
+func enabled(hooker zero.Hooker, groupID int64) bool {
+	switch h := hooker.(type) {
+	case interface{ IsEnabled(groupID int64) bool }:
+		return h.IsEnabled(groupID)
+	case interface{ Hookers() []zero.Hooker }:
+		for _, sub := range h.Hookers() {
+			if !enabled(sub, groupID) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func title(info zero.PluginInfo) string {
+	t := "插件 " + info.PluginName
+	if info.Version != "" {
+		t += " v" + info.Version
+	}
+	if info.Author != "" {
+		t += " (by " + info.Author + ")"
+	}
+	if info.Details != "" {
+		t += ": " + info.Details
+	}
+	return t
+}
+
+func (s section) text(prefix string) string {
+	var sb strings.Builder
+	sb.WriteString(s.title)
+	for _, c := range s.commands {
+		sb.WriteString("\n  ")
+		sb.WriteString(prefix + c.Name)
+		if c.Description != "" {
+			sb.WriteString("  " + c.Description)
+		}
+	}
+	return sb.String()
+}
+
+// detail 返回单个命令的详细帮助
+func detail(c *zero.CommandInfo, prefix string) string {
+	var sb strings.Builder
+	sb.WriteString("命令: " + prefix + c.Name)
+	if len(c.Aliases) > 0 {
+		sb.WriteString("\n别名: " + prefix + strings.Join(c.Aliases, " "+prefix))
+	}
+	if c.Usage != "" {
+		sb.WriteString("\n用法: " + prefix + c.Usage)
+	}
+	if c.Description != "" {
+		sb.WriteString("\n说明: " + c.Description)
+	}
+	if len(c.Examples) > 0 {
+		sb.WriteString("\n示例:")
+		for _, e := range c.Examples {
+			sb.WriteString("\n  " + e)
+		}
+	}
+	return sb.String()
+}
+
+// send 发送帮助, 群聊中过长时以合并转发发送
+func send(event zero.Event, sections []section, prefix string, threshold int) {
+	if len(sections) == 0 {
+		zero.Send(event, "当前没有可用的命令")
+		return
+	}
+	texts := make([]string, len(sections))
+	lines := 0
+	for i, s := range sections {
+		texts[i] = s.text(prefix)
+		lines += len(s.commands) + 1
+	}
+	if threshold <= 0 || lines <= threshold || event.GroupID == 0 {
+		zero.Send(event, strings.Join(texts, "\n\n"))
+		return
+	}
+	name := "ZeroBot"
+	if len(zero.BotConfig.NickName) > 0 {
+		name = zero.BotConfig.NickName[0]
+	}
+	uin, _ := strconv.ParseInt(zero.BotConfig.SelfID, 10, 64)
+	forward := message.NewForward()
+	for _, text := range texts {
+		forward.Custom(name, uin, message.Message{message.Text(text)})
+	}
+	_, _ = zero.SendForward(event, forward.Build())
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package help
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension/manager"
+)
+
+type testPlugin struct{}
+
+func (testPlugin) GetPluginInfo() zero.PluginInfo {
+	return zero.PluginInfo{Author: "test", PluginName: "test", Version: "1.0", Details: "测试"}
+}
+
+func (testPlugin) Start() {}
+
+// testManager 不持久化状态的 manager.Manager
+type testManager map[int64]bool
+
+func (m testManager) Hook() zero.Rule {
+	return func(event *zero.Event, _ zero.State) bool { return m.IsEnabled(event.GroupID) }
+}
+
+func (m testManager) Enable(groupID int64)  { delete(m, groupID) }
+func (m testManager) Disable(groupID int64) { m[groupID] = true }
+func (m testManager) IsEnabled(groupID int64) bool {
+	return !m[groupID]
+}
+
+// legacyManager 没有实现 IsEnabled 的 manager.Manager
+type legacyManager struct{}
+
+func (legacyManager) Hook() zero.Rule {
+	return func(*zero.Event, zero.State) bool { return true }
+}
+
+func (legacyManager) Enable(int64)  {}
+func (legacyManager) Disable(int64) {}
+
+func TestCollect(t *testing.T) {
+	zero.BotConfig.SuperUsers = []string{"1"}
+	defer func() { zero.BotConfig.SuperUsers = nil }()
+	zero.RegisterPlugin(testPlugin{})
+	var m manager.Manager = testManager{}
+	zero.AddHook(m)
+
+	echo := zero.OnCommandGroup([]string{"echo", "复读"}).SetDescription("复读消息").SetUsage("echo <内容>")
+	ban := zero.OnCommand("ban").SetPermission(zero.SuperUserPermission)
+	defer echo.Delete()
+	defer ban.Delete()
+
+	names := func(event *zero.Event) []string {
+		var ret []string
+		for _, s := range collect(event) {
+			for _, c := range s.commands {
+				ret = append(ret, c.Name)
+			}
+		}
+		return ret
+	}
+	member := &zero.Event{GroupID: 100, UserID: 2, Sender: &zero.User{Role: "member"}}
+	superuser := &zero.Event{GroupID: 100, UserID: 1, Sender: &zero.User{Role: "member"}}
+	assert.Equal(t, []string{"echo"}, names(member))
+	assert.Equal(t, []string{"echo", "ban"}, names(superuser))
+
+	sections := collect(superuser)
+	assert.Len(t, sections, 1)
+	assert.Equal(t, "插件 test v1.0 (by test): 测试\n  /echo  复读消息\n  /ban", sections[0].text("/"))
+	assert.Equal(t, "命令: /echo\n别名: /复读\n用法: /echo <内容>\n说明: 复读消息", detail(echo.Command, "/"))
+
+	m.Disable(100)
+	assert.Empty(t, names(superuser))
+	assert.Equal(t, []string{"echo"}, names(&zero.Event{UserID: 2, Sender: &zero.User{}})) // 私聊不受群内禁用影响
+
+	var legacy manager.Manager = legacyManager{}
+	assert.True(t, enabled(legacy, 100))
+	assert.False(t, enabled(zero.MultiHooker(legacy, m), 100))
+}
@@ -4,6 +4,7 @@ package kv
 import (
 	log "github.com/sirupsen/logrus"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 var db *leveldb.DB
@@ -21,6 +22,7 @@ type Bucket interface {
 	Get(k []byte) ([]byte, error)
 	Put(k []byte, v []byte) error
 	Delete(k []byte) error
+	ForEach(iterator func(k, v []byte) bool) error
 }
 
 type bucket struct {
@@ -34,7 +36,9 @@ func New(name string) Bucket {
 	return &bucket{name: []byte(name)}
 }
 
-func pack(name []byte, k []byte) []byte { return append(append(name, 0x02), k...) }
+func pack(name []byte, k []byte) []byte {
+	return append(append(append(make([]byte, 0, len(name)+1+len(k)), name...), 0x02), k...)
+}
 
 // Get returns a value for the given key from the default bucket.
 func Get(k []byte) ([]byte, error) { return defaultBucket.Get(k) }
@@ -59,3 +63,20 @@ func Delete(k []byte) error { return defaultBucket.Delete(k) }
 func (b *bucket) Delete(k []byte) error {
 	return db.Delete(pack(b.name, k), nil)
 }
+
+// ForEach iterates through key value pairs in the default bucket.
+func ForEach(iterator func(k, v []byte) bool) error { return defaultBucket.ForEach(iterator) }
+
+// ForEach iterates through key value pairs in the bucket,
+// the slices passed to iterator are only valid until it returns.
+func (b *bucket) ForEach(iterator func(k, v []byte) bool) error {
+	prefix := pack(b.name, nil)
+	iter := db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if !iterator(iter.Key()[len(prefix):], iter.Value()) {
+			break
+		}
+	}
+	return iter.Error()
+}
@@ -66,6 +66,16 @@ func (m *manager) Disable(groupID int64) {
 	_ = bucket.Put([]byte(m.service), pack(m.states))
 }
 
+// IsEnabled returns whether the group can pass the manager.
+func (m *manager) IsEnabled(groupID int64) bool {
+	m.RLock()
+	defer m.RUnlock()
+	if st, ok := m.states[groupID]; ok {
+		return st
+	}
+	return !m.options.DisableOnDefault
+}
+
 // Hook impls the zero.Hooker, so you can use zero.AddHook to the file.
 func (m *manager) Hook() zero.Rule {
 	return func(event *zero.Event, state zero.State) bool {
@@ -0,0 +1,78 @@
+// Package prefix provides per-group command prefixes persisted in the kv store.
+//
+// Import this package to make zero.CommandRule use the group's own prefixes.
+package prefix
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension/kv"
+)
+
+var (
+	bucket = kv.New("prefix")
+	cache  = map[int64]zero.Prefixes{}
+	mu     = sync.RWMutex{}
+)
+
+func init() {
+	zero.SetGroupPrefixProvider(Lookup)
+}
+
+// Set sets the command prefixes of the group, calling
+// without prefixes means commands in the group need no prefix.
+func Set(groupID int64, prefixes ...string) error {
+	if prefixes == nil {
+		prefixes = []string{}
+	}
+	data, err := json.Marshal(prefixes)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if err = bucket.Put(key(groupID), data); err != nil {
+		return err
+	}
+	cache[groupID] = prefixes
+	return nil
+}
+
+// Reset removes the group's prefixes, so the group
+// will use the prefixes in zero.BotConfig again.
+func Reset(groupID int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := bucket.Delete(key(groupID)); err != nil {
+		return err
+	}
+	cache[groupID] = nil
+	return nil
+}
+
+// Lookup returns the prefixes of the group, if the group
+// has not set its prefixes, ok will be false.
+func Lookup(groupID int64) (prefixes zero.Prefixes, ok bool) {
+	mu.RLock()
+	prefixes, ok = cache[groupID]
+	mu.RUnlock()
+	if !ok {
+		data, err := bucket.Get(key(groupID))
+		if err == nil {
+			_ = json.Unmarshal(data, &prefixes)
+		}
+		mu.Lock()
+		cache[groupID] = prefixes
+		mu.Unlock()
+	}
+	return prefixes, prefixes != nil
+}
+
+func key(groupID int64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(groupID))
+	return b
+}
@@ -0,0 +1,36 @@
+package prefix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+func TestPrefix(t *testing.T) {
+	zero.BotConfig.CommandPrefixes = []string{"/", "#"}
+	defer func() { zero.BotConfig.CommandPrefixes = nil }()
+
+	const group = 10001
+	defer func() { _ = Reset(group) }()
+
+	_, ok := Lookup(group)
+	assert.False(t, ok)
+	assert.Equal(t, zero.Prefixes{"/", "#"}, zero.CommandPrefixes(group))
+
+	assert.NoError(t, Set(group, "!", "！"))
+	prefixes, ok := Lookup(group)
+	assert.True(t, ok)
+	assert.Equal(t, zero.Prefixes{"!", "！"}, prefixes)
+	assert.Equal(t, zero.Prefixes{"!", "！"}, zero.CommandPrefixes(group))
+	assert.Equal(t, zero.Prefixes{"/", "#"}, zero.CommandPrefixes(0)) // 私聊使用全局前缀
+
+	assert.NoError(t, Set(group)) // 不需要前缀
+	assert.Equal(t, zero.Prefixes{""}, zero.CommandPrefixes(group))
+
+	assert.NoError(t, Reset(group))
+	_, ok = Lookup(group)
+	assert.False(t, ok)
+	assert.Equal(t, zero.Prefixes{"/", "#"}, zero.CommandPrefixes(group))
+}
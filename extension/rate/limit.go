@@ -0,0 +1,127 @@
+package rate
+
+import (
+	"sync/atomic"
+	"time"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+// Scope is the scope of a matcher limit.
+type Scope uint8
+
+const (
+	// ScopeUser 每个用户单独计算
+	ScopeUser Scope = iota
+	// ScopeGroup 每个群单独计算, 私聊时每个用户单独计算
+	ScopeGroup
+	// ScopeGlobal 所有事件共同计算
+	ScopeGlobal
+)
+
+// Options holds the parameters of a matcher limit.
+type Options struct {
+	// Interval 冷却时间, 为 0 时不限制频率
+	Interval time.Duration
+	// Burst 冷却前最多连续触发的次数, 默认为 1
+	Burst int
+	// Scope 冷却的范围
+	Scope Scope
+	// Key 自定义冷却的范围, 设置后忽略 Scope
+	Key KeyFunc
+	// MaxConcurrent 最多同时运行的 Handler 数, 为 0 时不限制
+	MaxConcurrent int32
+	// Reply 被 Middleware 限制时的回复, 为 nil 时不回复
+	Reply interface{}
+}
+
+// Limit limits the frequency and concurrency of a matcher.
+//
+// 次数只在 Middleware 中, 即 Matcher 的所有 Rule 通过后消耗, 被限制的事件不会运行 Handler,
+// 回复 Reply 后阻断后续 Matcher; 同时使用 Rule 时, 没有剩余次数的事件不会回复, 而是交给后续的 Matcher 处理
+type Limit struct {
+	options  Options
+	limiters *LimiterManager
+	running  int32
+}
+
+// NewLimit returns a matcher limit with options.
+func NewLimit(o Options) *Limit {
+	if o.Burst <= 0 {
+		o.Burst = 1
+	}
+	if o.Key == nil {
+		switch o.Scope {
+		case ScopeUser:
+			o.Key = UserKey
+		case ScopeGroup:
+			o.Key = GroupKey
+		default:
+			o.Key = GlobalKey
+		}
+	}
+	l := &Limit{options: o}
+	if o.Interval > 0 {
+		l.limiters = NewManager(o.Interval, o.Burst)
+	}
+	return l
+}
+
+// Rule returns a rule which fails when no token is left without taking
+// one, use it together with Middleware to let limited events fall through
+// to lower-priority matchers.
+func (l *Limit) Rule() zero.Rule {
+	return func(event *zero.Event, _ zero.State) bool {
+		return l.allow(event)
+	}
+}
+
+// Middleware returns a middleware which limits the frequency and concurrency.
+func (l *Limit) Middleware() zero.Middleware {
+	return func(next zero.Handler) zero.Handler {
+		return func(matcher *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+			if max := l.options.MaxConcurrent; max > 0 {
+				defer atomic.AddInt32(&l.running, -1)
+				if atomic.AddInt32(&l.running, 1) > max {
+					return l.limited(matcher, &event)
+				}
+			}
+			if !l.acquire(&event) {
+				return l.limited(matcher, &event)
+			}
+			return next(matcher, event, state)
+		}
+	}
+}
+
+// allow 检查是否还有剩余次数, 不消耗次数
+func (l *Limit) allow(event *zero.Event) bool {
+	if l.limiters == nil {
+		return true
+	}
+	lim := l.limiters.LoadKey(l.options.Key(event))
+	lim.Lock()
+	defer lim.Unlock()
+	lim.advance(time.Now())
+	return lim.tokens >= 1
+}
+
+func (l *Limit) acquire(event *zero.Event) bool {
+	if l.limiters == nil {
+		return true
+	}
+	return l.limiters.LoadKey(l.options.Key(event)).Acquire()
+}
+
+// limited 回复被限制的事件并阻断后续 Matcher
+func (l *Limit) limited(matcher *zero.Matcher, event *zero.Event) zero.Response {
+	l.reply(event)
+	matcher.Block = true
+	return zero.FinishResponse
+}
+
+func (l *Limit) reply(event *zero.Event) {
+	if l.options.Reply != nil {
+		zero.Send(*event, l.options.Reply)
+	}
+}
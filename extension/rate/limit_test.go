@@ -0,0 +1,50 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+)
+
+func TestLimit(t *testing.T) {
+	l := NewLimit(Options{Interval: time.Hour, Burst: 2, MaxConcurrent: 1})
+	event := &zero.Event{UserID: 1}
+	rule := l.Rule()
+
+	var calls int
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := l.Middleware()(func(*zero.Matcher, zero.Event, zero.State) zero.Response {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+		}
+		return zero.FinishResponse
+	})
+	run := func() bool {
+		m := &zero.Matcher{}
+		handler(m, *event, zero.State{})
+		return !m.Block // 被限制时阻断后续 Matcher
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, rule(event, zero.State{})) // Rule 不消耗次数
+	}
+	done := make(chan bool)
+	go func() { done <- run() }()
+	<-started
+	assert.False(t, run()) // 超过并发数, 不消耗次数
+	close(release)
+	assert.True(t, <-done)
+
+	assert.True(t, rule(event, zero.State{}))
+	assert.True(t, run())
+	assert.False(t, rule(event, zero.State{}))
+	assert.False(t, run())
+	assert.Equal(t, 2, calls)
+	assert.True(t, rule(&zero.Event{UserID: 2}, zero.State{}))
+}
@@ -3,33 +3,136 @@
 package rate
 
 import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
 )
 
+// KeyFunc returns the limiter key of the event.
+type KeyFunc func(event *zero.Event) string
+
 // LimiterManager ...
 type LimiterManager struct {
-	limiters sync.Map
-	interval time.Duration
-	burst    int
+	limiters  sync.Map
+	interval  time.Duration
+	burst     int
+	ttl       time.Duration
+	keyFunc   KeyFunc
+	mu        sync.Mutex
+	lastSweep time.Time
 }
 
 // NewManager ..
 func NewManager(interval time.Duration, burst int) *LimiterManager {
 	return &LimiterManager{
-		interval: interval,
-		burst:    burst,
+		interval:  interval,
+		burst:     burst,
+		ttl:       interval * time.Duration(burst), // 没有预约欠账时, 空闲超过该时间的 Limiter 与新建的 Limiter 相同
+		keyFunc:   UserKey,
+		lastSweep: time.Now(),
 	}
 }
 
+// SetTTL sets the time after which idle limiters are evicted.
+func (l *LimiterManager) SetTTL(ttl time.Duration) *LimiterManager {
+	l.ttl = ttl
+	return l
+}
+
+// SetKeyFunc sets the key func used by Hook.
+func (l *LimiterManager) SetKeyFunc(keyFunc KeyFunc) *LimiterManager {
+	l.keyFunc = keyFunc
+	return l
+}
+
 // Load ...
 func (l *LimiterManager) Load(key int64) *Limiter {
+	return l.LoadKey(strconv.FormatInt(key, 10))
+}
+
+// LoadKey returns the limiter of the key, creates one if not exist.
+func (l *LimiterManager) LoadKey(key string) *Limiter {
+	l.sweep()
 	if val, ok := l.limiters.Load(key); ok {
 		return val.(*Limiter)
 	}
-	val := NewLimiter(l.interval, l.burst)
-	l.limiters.Store(key, val)
-	return val
+	val, _ := l.limiters.LoadOrStore(key, NewLimiter(l.interval, l.burst))
+	return val.(*Limiter)
+}
+
+// Hook impls the zero.Hooker, events limited by the key func will not be handled.
+func (l *LimiterManager) Hook() zero.Rule {
+	return l.Rule(l.keyFunc)
+}
+
+// Rule returns a rule which acquires a token from the limiter of the event's key.
+func (l *LimiterManager) Rule(keyFunc KeyFunc) zero.Rule {
+	return func(event *zero.Event, _ zero.State) bool {
+		return l.LoadKey(keyFunc(event)).Acquire()
+	}
+}
+
+// sweep 删除空闲超过 ttl 且令牌已补满的 Limiter, 预约产生的欠账还清前不会删除
+func (l *LimiterManager) sweep() {
+	if l.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) < l.ttl {
+		l.mu.Unlock()
+		return
+	}
+	l.lastSweep = now
+	l.mu.Unlock()
+	l.limiters.Range(func(key, value interface{}) bool {
+		lim := value.(*Limiter)
+		lim.Lock()
+		idle := now.Sub(lim.lastTime)
+		full := lim.tokens+lim.tokensFromDuration(idle) >= float64(lim.burst)
+		lim.Unlock()
+		if idle > l.ttl && full {
+			l.limiters.Delete(key)
+		}
+		return true
+	})
+}
+
+// Key joins ids into a composite key.
+func Key(ids ...int64) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(s, ":")
+}
+
+// UserKey limits each user.
+func UserKey(event *zero.Event) string {
+	return Key(event.UserID)
+}
+
+// GroupKey limits each group, private messages are limited by user.
+func GroupKey(event *zero.Event) string {
+	if event.GroupID == 0 {
+		return Key(0, event.UserID)
+	}
+	return Key(event.GroupID)
+}
+
+// GroupUserKey limits each user in each group.
+func GroupUserKey(event *zero.Event) string {
+	return Key(event.GroupID, event.UserID)
+}
+
+// GlobalKey limits all events together.
+func GlobalKey(*zero.Event) string {
+	return ""
 }
 
 // Limiter controls the frequency of handling events.
@@ -74,6 +177,86 @@ func (lim *Limiter) AcquireN(n int) bool {
 	return false
 }
 
+// Reservation holds tokens reserved from the limiter.
+type Reservation struct {
+	lim       *Limiter
+	ok        bool
+	tokens    float64
+	timeToAct time.Time
+}
+
+// Reserve reserves a token from the limiter.
+func (lim *Limiter) Reserve() *Reservation {
+	return lim.ReserveN(1)
+}
+
+// ReserveN reserves n tokens from the limiter, the tokens can be
+// used after Delay, if n exceeds the burst, the reservation is not OK.
+func (lim *Limiter) ReserveN(n int) *Reservation {
+	lim.Lock()
+	defer lim.Unlock()
+	now := time.Now()
+	lim.advance(now)
+	r := &Reservation{lim: lim, ok: n <= lim.burst, tokens: float64(n), timeToAct: now}
+	if !r.ok {
+		return r
+	}
+	lim.tokens -= r.tokens
+	if lim.tokens < 0 {
+		r.timeToAct = now.Add(lim.durationFromTokens(-lim.tokens))
+	}
+	return r
+}
+
+// OK returns whether the tokens can be reserved.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait until the reserved tokens can be used.
+func (r *Reservation) Delay() time.Duration {
+	if d := time.Until(r.timeToAct); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel returns the reserved tokens to the limiter.
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	r.ok = false
+	r.lim.Lock()
+	defer r.lim.Unlock()
+	r.lim.advance(time.Now())
+	r.lim.tokens += r.tokens
+	if burst := float64(r.lim.burst); r.lim.tokens > burst {
+		r.lim.tokens = burst
+	}
+}
+
+// Wait blocks until a token can be acquired or ctx is done.
+func (lim *Limiter) Wait(ctx context.Context) error {
+	r := lim.Reserve()
+	if !r.OK() {
+		return errors.New("rate: burst of limiter is less than 1")
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
 func (lim *Limiter) advance(now time.Time) {
 	last := lim.lastTime
 	elapsed := now.Sub(last)
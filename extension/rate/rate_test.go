@@ -0,0 +1,52 @@
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Reserve(t *testing.T) {
+	lim := NewLimiter(time.Second, 1)
+	assert.Equal(t, time.Duration(0), lim.Reserve().Delay())
+	r := lim.Reserve()
+	assert.True(t, r.OK())
+	assert.InDelta(t, float64(time.Second), float64(r.Delay()), float64(50*time.Millisecond))
+	r.Cancel()
+	assert.False(t, lim.Acquire())
+	assert.False(t, lim.ReserveN(2).OK())
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	lim := NewLimiter(50*time.Millisecond, 1)
+	assert.NoError(t, lim.Wait(context.Background()))
+	start := time.Now()
+	assert.NoError(t, lim.Wait(context.Background()))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, lim.Wait(ctx))
+}
+
+func TestLimiterManager_Sweep(t *testing.T) {
+	l := NewManager(10*time.Millisecond, 1)
+	lim := l.LoadKey(Key(1, 2))
+	assert.True(t, lim == l.LoadKey("1:2"))
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, lim == l.LoadKey("1:2"))
+}
+
+func TestLimiterManager_SweepDebt(t *testing.T) {
+	l := NewManager(20*time.Millisecond, 1)
+	lim := l.LoadKey("1")
+	for i := 0; i < 3; i++ {
+		lim.Reserve() // 欠下两个令牌, 60ms 后才补满
+	}
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, lim == l.LoadKey("1"))
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, lim == l.LoadKey("1"))
+}
@@ -0,0 +1,487 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoTime is returned by Parse when the text does not start with a time expression.
+var ErrNoTime = errors.New("reminder: no time found")
+
+// When is the time of a reminder.
+type When struct {
+	At   time.Time // 一次性提醒的时间
+	Cron string    // 周期性提醒的 cron 表达式, 不为空时忽略 At
+}
+
+// Recurring reports whether the reminder repeats.
+func (w When) Recurring() bool { return w.Cron != "" }
+
+// 默认提醒时间
+const defaultHour = 9
+
+// 相对时间的上限
+const maxDuration = 5 * 365 * 24 * time.Hour
+
+const num = `(\d+|[零一二两三四五六七八九十百]+)`
+
+var (
+	// 中文相对时间, 如 "1小时30分钟后" "半小时后" "三天以后"
+	zhDuration = regexp.MustCompile(`^(?:` + num + `\s*个?\s*(半)?\s*(秒钟?|分钟?|小时|钟头|天|周|星期)(半)?|(半)\s*个?\s*(小时|钟头|分钟))\s*`)
+	zhAfter    = regexp.MustCompile(`^(?:后|以后|之后)`)
+	// 英文相对时间, 如 "in 2 hours" "in an hour and 30 minutes"
+	enIn       = regexp.MustCompile(`(?i)^in\s+`)
+	enDuration = regexp.MustCompile(`(?i)^(?:and\s+|,\s*)?(\d+|an?|half\s+an?)\s*(seconds?|secs?|s|minutes?|mins?|m|hours?|hrs?|h|days?|d|weeks?|w)\b\s*`)
+
+	zhEvery   = regexp.MustCompile(`^(?:每天|每日|每个?工作日|每个?(?:周|星期|礼拜)([一二三四五六日天1-7])|每个?月` + num + `[号日])\s*`)
+	zhDay     = regexp.MustCompile(`^(今天|今晚|明天|明晚|后天|大后天)\s*`)
+	zhWeekday = regexp.MustCompile(`^(下*)(?:周|星期|礼拜)([一二三四五六日天1-7])\s*`)
+	zhDate    = regexp.MustCompile(`^(?:(\d{4})\s*年\s*)?` + num + `\s*月\s*` + num + `\s*[日号]\s*|^` + num + `\s*[号]\s*`)
+	isoDate   = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\s*`)
+	zhPeriod  = regexp.MustCompile(`^(早上|早晨|清晨|上午|中午|下午|傍晚|晚上|夜里|凌晨|半夜)\s*`)
+	zhClock   = regexp.MustCompile(`^` + num + `\s*[点时](?:(半)|(一刻)|(三刻)|` + num + `分?)?\s*`)
+	clock     = regexp.MustCompile(`(?i)^(?:at\s+)?(\d{1,2}):(\d{2})\s*(am|pm|a\.m\.|p\.m\.)?\s*`)
+
+	enEvery   = regexp.MustCompile(`(?i)^(?:every\s*day|daily|every\s+weekday|every\s+(monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tue|wed|thu|fri|sat|sun))\b\s*`)
+	enDay     = regexp.MustCompile(`(?i)^(today|tonight|tomorrow)\b\s*`)
+	enWeekday = regexp.MustCompile(`(?i)^(?:on\s+)?(next\s+)?(monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tue|wed|thu|fri|sat|sun)\b\s*`)
+	enClock   = regexp.MustCompile(`(?i)^(?:(?:at\s+)?(\d{1,2})\s*(am|pm|a\.m\.|p\.m\.)|at\s+(\d{1,2})\b|(?:at\s+)?(noon|midnight)\b)\s*`)
+
+	// 时间与内容之间的连接词
+	connective = regexp.MustCompile(`(?i)^(?:[,，:：]\s*|(?:提醒我|叫我|通知我|remind\s+me\s+(?:to\s+)?|to\s+)\s*)+`)
+)
+
+var zhWeekdays = map[string]time.Weekday{
+	"一": time.Monday, "二": time.Tuesday, "三": time.Wednesday, "四": time.Thursday,
+	"五": time.Friday, "六": time.Saturday, "日": time.Sunday, "天": time.Sunday,
+	"1": time.Monday, "2": time.Tuesday, "3": time.Wednesday, "4": time.Thursday,
+	"5": time.Friday, "6": time.Saturday, "7": time.Sunday,
+}
+
+var enWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Parse parses the time expression at the beginning of text relative to now,
+// and returns the rest of text as the content of the reminder.
+//
+// Supported expressions are like "10分钟后" "明天下午3点" "周五 18:30" "5月1日"
+// "每周一 9:00" "每天早上8点半" "in 2 hours" "tomorrow at 3pm" "every monday 9am".
+func Parse(text string, now time.Time) (When, string, error) {
+	text = strings.TrimSpace(text)
+	if d, rest, ok := parseDuration(text); ok {
+		if d > maxDuration {
+			return When{}, trimContent(rest), fmt.Errorf("reminder: duration exceeds %d days", maxDuration/(24*time.Hour))
+		}
+		return When{At: now.Add(d)}, trimContent(rest), nil
+	}
+	p := parser{text: text, now: now, loc: now.Location()}
+	if !p.parse() {
+		return When{}, text, ErrNoTime
+	}
+	w, err := p.when()
+	return w, trimContent(p.text), err
+}
+
+func trimContent(s string) string {
+	return strings.TrimSpace(connective.ReplaceAllString(strings.TrimSpace(s), ""))
+}
+
+// parseDuration 解析相对时间, 超过 maxDuration 时返回 maxDuration 以上的值
+func parseDuration(text string) (time.Duration, string, bool) {
+	var total time.Duration
+	rest := text
+	if loc := enIn.FindStringIndex(rest); loc != nil {
+		rest = rest[loc[1]:]
+		for {
+			m := enDuration.FindStringSubmatch(rest)
+			if m == nil {
+				break
+			}
+			var n float64
+			switch v := strings.ToLower(strings.Join(strings.Fields(m[1]), " ")); v {
+			case "a", "an":
+				n = 1
+			case "half a", "half an":
+				n = 0.5
+			default:
+				i, _ := strconv.Atoi(v)
+				n = float64(i)
+			}
+			total = addDuration(total, n, unit(strings.ToLower(m[2])))
+			rest = rest[len(m[0]):]
+		}
+		return total, rest, total > 0
+	}
+	for {
+		m := zhDuration.FindStringSubmatch(rest)
+		if m == nil {
+			break
+		}
+		if m[5] != "" { // 半小时
+			total = addDuration(total, 0.5, unit(m[6]))
+		} else {
+			n, ok := parseNumber(m[1])
+			if !ok {
+				return 0, text, false
+			}
+			u := unit(m[3])
+			total = addDuration(total, float64(n), u)
+			if m[2] != "" || m[4] != "" { // 一个半小时, 两天半
+				total = addDuration(total, 0.5, u)
+			}
+		}
+		rest = rest[len(m[0]):]
+	}
+	if total == 0 {
+		return 0, text, false
+	}
+	loc := zhAfter.FindStringIndex(rest)
+	if loc == nil {
+		return 0, text, false
+	}
+	return total, rest[loc[1]:], true
+}
+
+// addDuration 返回 total + n*u, 超过 maxDuration 时返回 maxDuration + 1 以避免溢出
+func addDuration(total time.Duration, n float64, u time.Duration) time.Duration {
+	if total > maxDuration || n > float64(maxDuration-total)/float64(u) {
+		return maxDuration + 1
+	}
+	return total + time.Duration(n*float64(u))
+}
+
+func unit(s string) time.Duration {
+	switch {
+	case strings.HasPrefix(s, "秒"), strings.HasPrefix(s, "s"):
+		return time.Second
+	case strings.HasPrefix(s, "分"), strings.HasPrefix(s, "m"):
+		return time.Minute
+	case s == "小时", s == "钟头", strings.HasPrefix(s, "h"):
+		return time.Hour
+	case s == "天", strings.HasPrefix(s, "d"):
+		return 24 * time.Hour
+	default: // 周, 星期, week
+		return 7 * 24 * time.Hour
+	}
+}
+
+// parser 解析绝对时间和周期时间
+type parser struct {
+	text string
+	now  time.Time
+	loc  *time.Location
+
+	cron    string // cron 的日期部分: "日 月 星期"
+	date    time.Time
+	hasDate bool
+	weekday bool // 日期由星期推出, 时间已过时顺延一周
+	period  string
+	hour    int
+	minute  int
+	hasTime bool
+	err     error // 日期超出范围
+}
+
+func (p *parser) consume(re *regexp.Regexp) []string {
+	m := re.FindStringSubmatch(p.text)
+	if m != nil {
+		p.text = p.text[len(m[0]):]
+	}
+	return m
+}
+
+func (p *parser) parse() bool {
+	p.parseDate()
+	if m := p.consume(zhPeriod); m != nil {
+		p.period = m[1]
+	}
+	p.parseClock()
+	if p.hasTime && !p.hasDate && p.cron == "" { // 日期在时间之后, 如 "at 3pm tomorrow"
+		p.parseDate()
+	}
+	return p.cron != "" || p.hasDate || p.hasTime
+}
+
+func (p *parser) day(offset int) time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day()+offset, 0, 0, 0, 0, p.loc)
+}
+
+func (p *parser) parseDate() {
+	if m := p.consume(zhEvery); m != nil {
+		switch {
+		case m[1] != "":
+			p.cron = fmt.Sprintf("* * %d", zhWeekdays[m[1]])
+		case m[2] != "":
+			d, _ := parseNumber(m[2])
+			if d < 1 || d > 31 {
+				p.err = fmt.Errorf("reminder: invalid day of month %d", d)
+			}
+			p.cron = fmt.Sprintf("%d * *", d)
+		case strings.Contains(m[0], "工作日"):
+			p.cron = "* * 1-5"
+		default:
+			p.cron = "* * *"
+		}
+		return
+	}
+	if m := p.consume(enEvery); m != nil {
+		switch s := strings.ToLower(m[0]); {
+		case m[1] != "":
+			p.cron = fmt.Sprintf("* * %d", enWeekdays[strings.ToLower(m[1])[:3]])
+		case strings.Contains(s, "weekday"):
+			p.cron = "* * 1-5"
+		default:
+			p.cron = "* * *"
+		}
+		return
+	}
+	p.hasDate = true
+	if m := p.consume(zhDay); m != nil {
+		switch m[1] {
+		case "今天", "今晚":
+			p.date = p.day(0)
+		case "明天", "明晚":
+			p.date = p.day(1)
+		case "后天":
+			p.date = p.day(2)
+		case "大后天":
+			p.date = p.day(3)
+		}
+		if strings.HasSuffix(m[1], "晚") {
+			p.period = "晚上"
+		}
+		return
+	}
+	if m := p.consume(enDay); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "today":
+			p.date = p.day(0)
+		case "tonight":
+			p.date = p.day(0)
+			p.period = "晚上"
+		case "tomorrow":
+			p.date = p.day(1)
+		}
+		return
+	}
+	if m := p.consume(zhWeekday); m != nil {
+		p.weekdayDate(zhWeekdays[m[2]], len(m[1])/len("下"))
+		return
+	}
+	if m := p.consume(enWeekday); m != nil {
+		next := 0
+		if m[1] != "" {
+			next = 1
+		}
+		p.weekdayDate(enWeekdays[strings.ToLower(m[2])[:3]], next)
+		return
+	}
+	if m := p.consume(isoDate); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		p.date = time.Date(y, time.Month(mo), d, 0, 0, 0, 0, p.loc)
+		p.checkDate(mo, d)
+		return
+	}
+	if m := p.consume(zhDate); m != nil {
+		if m[4] != "" { // 只有日
+			d, _ := parseNumber(m[4])
+			mo := p.now.Month()
+			if time.Date(p.now.Year(), mo, d, 0, 0, 0, 0, p.loc).Before(p.day(0)) {
+				mo++
+			}
+			p.date = time.Date(p.now.Year(), mo, d, 0, 0, 0, 0, p.loc)
+			p.checkDate(int(p.date.Month()), d)
+			return
+		}
+		mo, _ := parseNumber(m[2])
+		d, _ := parseNumber(m[3])
+		y := p.now.Year()
+		if m[1] != "" {
+			y, _ = strconv.Atoi(m[1])
+		}
+		p.date = time.Date(y, time.Month(mo), d, 0, 0, 0, 0, p.loc)
+		if m[1] == "" && p.date.Before(p.day(0)) {
+			p.date = time.Date(y+1, time.Month(mo), d, 0, 0, 0, 0, p.loc)
+		}
+		p.checkDate(mo, d)
+		return
+	}
+	p.hasDate = false
+}
+
+// checkDate 检查日期是否存在, time.Date 会把 "2月30日" 规范化为 3月2日
+func (p *parser) checkDate(month, day int) {
+	if month < 1 || month > 12 || day < 1 || day > 31 || p.date.Month() != time.Month(month) || p.date.Day() != day {
+		p.err = fmt.Errorf("reminder: invalid date %d月%d日", month, day)
+	}
+}
+
+// weekdayDate 计算星期对应的日期, weeks 为 "下" 的个数
+func (p *parser) weekdayDate(wd time.Weekday, weeks int) {
+	if weeks == 0 {
+		p.date = p.day((int(wd) - int(p.now.Weekday()) + 7) % 7)
+		p.weekday = true
+		return
+	}
+	// 下周X 指下一个自然周(周一开始)中的星期X
+	monday := p.day(-((int(p.now.Weekday()) + 6) % 7))
+	p.date = monday.AddDate(0, 0, 7*weeks+(int(wd)+6)%7)
+}
+
+func (p *parser) parseClock() {
+	if m := p.consume(clock); m != nil {
+		p.hour, _ = strconv.Atoi(m[1])
+		p.minute, _ = strconv.Atoi(m[2])
+		p.meridiem(m[3])
+		p.hasTime = true
+		return
+	}
+	if m := p.consume(zhClock); m != nil {
+		p.hour, _ = parseNumber(m[1])
+		switch {
+		case m[2] != "":
+			p.minute = 30
+		case m[3] != "":
+			p.minute = 15
+		case m[4] != "":
+			p.minute = 45
+		case m[5] != "":
+			p.minute, _ = parseNumber(m[5])
+		}
+		p.hasTime = true
+		return
+	}
+	if m := p.consume(enClock); m != nil {
+		switch {
+		case m[1] != "":
+			p.hour, _ = strconv.Atoi(m[1])
+			p.meridiem(m[2])
+		case m[3] != "":
+			p.hour, _ = strconv.Atoi(m[3])
+		case strings.EqualFold(m[4], "noon"):
+			p.hour = 12
+		default:
+			p.hour = 0
+		}
+		p.hasTime = true
+		return
+	}
+	if p.period != "" { // 只有时段, 如 "明天上午"
+		p.hour = map[string]int{
+			"早上": 8, "早晨": 8, "清晨": 7, "上午": 9, "中午": 12, "下午": 15,
+			"傍晚": 18, "晚上": 20, "夜里": 22, "凌晨": 1, "半夜": 0,
+		}[p.period]
+		p.period = ""
+		p.hasTime = true
+	}
+}
+
+func (p *parser) meridiem(s string) {
+	switch strings.ToLower(strings.Replace(s, ".", "", -1)) {
+	case "am":
+		p.period = "上午"
+	case "pm":
+		p.period = "下午"
+	}
+}
+
+// clockHour 根据时段调整小时
+func (p *parser) clockHour() int {
+	h := p.hour
+	switch p.period {
+	case "下午", "傍晚", "晚上", "夜里":
+		if h < 12 {
+			h += 12
+		}
+	case "中午":
+		if h < 11 {
+			h += 12
+		}
+	case "凌晨", "半夜", "早上", "早晨", "清晨", "上午":
+		if h == 12 {
+			h = 0
+		}
+	}
+	return h
+}
+
+func (p *parser) when() (When, error) {
+	if p.err != nil {
+		return When{}, p.err
+	}
+	if p.hasTime && (p.hour > 24 || p.minute > 59) {
+		return When{}, fmt.Errorf("reminder: invalid time %d:%02d", p.hour, p.minute)
+	}
+	h, mi := defaultHour, 0
+	if p.hasTime {
+		h, mi = p.clockHour()%24, p.minute
+	}
+	if p.cron != "" {
+		return When{Cron: fmt.Sprintf("%d %d %s", mi, h, p.cron)}, nil
+	}
+	if !p.hasDate {
+		at := time.Date(p.now.Year(), p.now.Month(), p.now.Day(), h, mi, 0, 0, p.loc)
+		// 未指明上下午时取最近的一个, 如上午十点说 "3点" 指下午三点
+		if p.period == "" && h >= 1 && h < 12 && !at.After(p.now) {
+			if pm := at.Add(12 * time.Hour); pm.After(p.now) {
+				return When{At: pm}, nil
+			}
+		}
+		if !at.After(p.now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return When{At: at}, nil
+	}
+	at := time.Date(p.date.Year(), p.date.Month(), p.date.Day(), h, mi, 0, 0, p.loc)
+	if p.weekday && !at.After(p.now) {
+		at = at.AddDate(0, 0, 7)
+	}
+	if !at.After(p.now) {
+		return When{}, fmt.Errorf("reminder: %v has passed", at.Format("2006-01-02 15:04"))
+	}
+	return When{At: at}, nil
+}
+
+var cnDigits = map[rune]int{
+	'零': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// parseNumber 解析阿拉伯数字或不超过一千的中文数字
+func parseNumber(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	var total, digit int
+	for _, r := range s {
+		switch r {
+		case '百':
+			if digit == 0 {
+				digit = 1
+			}
+			total += digit * 100
+			digit = 0
+		case '十':
+			if digit == 0 {
+				digit = 1
+			}
+			total += digit * 10
+			digit = 0
+		default:
+			d, ok := cnDigits[r]
+			if !ok {
+				return 0, false
+			}
+			digit = d
+		}
+	}
+	return total + digit, true
+}
@@ -0,0 +1,67 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2021, 3, 3, 10, 0, 0, 0, loc) // 星期三
+	at := func(month time.Month, day, hour, min int) When {
+		return When{At: time.Date(2021, month, day, hour, min, 0, 0, loc)}
+	}
+	tests := []struct {
+		text    string
+		when    When
+		content string
+	}{
+		{"10分钟后 喝水", When{At: now.Add(10 * time.Minute)}, "喝水"},
+		{"一个半小时后提醒我开会", When{At: now.Add(90 * time.Minute)}, "开会"},
+		{"1小时30分钟以后 下班", When{At: now.Add(90 * time.Minute)}, "下班"},
+		{"in 2 hours drink water", When{At: now.Add(2 * time.Hour)}, "drink water"},
+		{"in an hour and 30 mins, call mom", When{At: now.Add(90 * time.Minute)}, "call mom"},
+		{"明天下午3点 开会", at(3, 4, 15, 0), "开会"},
+		{"明晚8点半 看电影", at(3, 4, 20, 30), "看电影"},
+		{"后天 交作业", at(3, 5, 9, 0), "交作业"},
+		{"周五 18:30 聚餐", at(3, 5, 18, 30), "聚餐"},
+		{"周三 9点 例会", at(3, 10, 9, 0), "例会"},
+		{"下周一上午十点 复盘", at(3, 8, 10, 0), "复盘"},
+		{"5月1日 放假", at(5, 1, 9, 0), "放假"},
+		{"2021-03-20 15:00 生日", at(3, 20, 15, 0), "生日"},
+		{"3点 取快递", at(3, 3, 15, 0), "取快递"},
+		{"中午12点 吃饭", at(3, 3, 12, 0), "吃饭"},
+		{"tomorrow at 3pm meeting", at(3, 4, 15, 0), "meeting"},
+		{"at 9am tomorrow standup", at(3, 4, 9, 0), "standup"},
+		{"next monday 9:15 review", at(3, 8, 9, 15), "review"},
+		{"tonight 11pm sleep", at(3, 3, 23, 0), "sleep"},
+		{"每周一 9:00 写周报", When{Cron: "0 9 * * 1"}, "写周报"},
+		{"每天早上8点半 跑步", When{Cron: "30 8 * * *"}, "跑步"},
+		{"每个工作日 18点 打卡", When{Cron: "0 18 * * 1-5"}, "打卡"},
+		{"每月1号 交房租", When{Cron: "0 9 1 * *"}, "交房租"},
+		{"every friday 5pm report", When{Cron: "0 17 * * 5"}, "report"},
+	}
+	for _, tt := range tests {
+		w, content, err := Parse(tt.text, now)
+		if assert.NoError(t, err, tt.text) {
+			assert.True(t, tt.when.At.Equal(w.At), "%s: %v", tt.text, w.At)
+			assert.Equal(t, tt.when.Cron, w.Cron, tt.text)
+			assert.Equal(t, tt.content, content, tt.text)
+		}
+	}
+
+	_, _, err := Parse("喝水", now)
+	assert.Equal(t, ErrNoTime, err)
+	_, _, err = Parse("今天 8点 早会", now)
+	assert.Error(t, err)
+	for _, text := range []string{"每月32号 交房租", "每月0号 交房租", "13月1日 放假", "2月30日 放假", "2021-04-31 放假", "0号 交房租", "31号 交房租"} {
+		_, _, err = Parse(text, time.Date(2021, 4, 10, 10, 0, 0, 0, loc))
+		assert.Error(t, err, text)
+	}
+	for _, text := range []string{"100000000天后 喝水", "99999999999周后 喝水", "in 100000000 days drink water", "2000天后 喝水"} {
+		_, _, err = Parse(text, now)
+		assert.Error(t, err, text)
+	}
+}
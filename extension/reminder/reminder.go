@@ -0,0 +1,294 @@
+// Package reminder is a reminder plugin, reminders are stored in the kv store
+// and delivered with an At mention by extension/scheduler.
+//
+//	/提醒 明天下午3点 开会
+//	/remind in 2 hours drink water
+//	/提醒 每周一 9:00 写周报
+//	/提醒列表
+//	/取消提醒 1
+package reminder
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension"
+	"github.com/wdvxdr1123/ZeroBot/extension/kv"
+	"github.com/wdvxdr1123/ZeroBot/extension/scheduler"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// MaxPerUser is the max number of pending reminders of a user.
+var MaxPerUser = 20
+
+// Reminder is a reminder of a user.
+type Reminder struct {
+	ID      string `json:"id"`
+	GroupID int64  `json:"group_id"` // 群号, 私聊时为 0
+	UserID  int64  `json:"user_id"`
+	Content string `json:"content"`
+	At      int64  `json:"at"`   // 一次性提醒的时间戳
+	Cron    string `json:"cron"` // 周期性提醒的 cron 表达式
+}
+
+var (
+	bucket = kv.New("reminder")
+	mu     sync.Mutex
+	jobs   = map[string]*scheduler.Job{} // 提醒 ID -> 定时任务
+)
+
+func init() {
+	zero.RegisterPlugin(&reminderPlugin{})
+}
+
+type reminderPlugin struct{}
+
+func (*reminderPlugin) GetPluginInfo() zero.PluginInfo {
+	return zero.PluginInfo{
+		Author:     "wdvxdr1123",
+		PluginName: "reminder",
+		Version:    "0.1.0",
+		Details:    "定时提醒",
+	}
+}
+
+func (*reminderPlugin) Start() {
+	zero.OnCommandGroup([]string{"提醒", "remind"}).
+		SetBlock(true).
+		SetUsage("提醒 <时间> <内容>").
+		SetDescription("添加提醒, 支持 \"10分钟后\" \"明天下午3点\" \"每周一 9:00\" \"in 2 hours\" 等时间").
+		SetExamples("/提醒 明天下午3点 开会", "/remind in 2 hours drink water").
+		Handle(handleAdd)
+	zero.OnCommandGroup([]string{"提醒列表", "reminders"}).
+		SetBlock(true).
+		SetDescription("查看自己的提醒").
+		Handle(handleList)
+	zero.OnCommandGroup([]string{"取消提醒", "unremind"}).
+		SetBlock(true).
+		SetUsage("取消提醒 <编号|全部>").
+		SetDescription("取消提醒列表中对应编号的提醒").
+		Handle(handleCancel)
+	load()
+}
+
+// Add adds a reminder and schedules it, invalid cron expressions are not stored.
+func Add(r *Reminder) error {
+	if r.ID == "" {
+		r.ID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	if r.Cron != "" {
+		if _, err := scheduler.ParseCron(r.Cron, nil); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err = bucket.Put([]byte(r.ID), data); err != nil {
+		return err
+	}
+	if err = schedule(r); err != nil {
+		_ = bucket.Delete([]byte(r.ID))
+		return err
+	}
+	return nil
+}
+
+// Remove cancels the reminder and deletes it from the kv store.
+func Remove(id string) error {
+	mu.Lock()
+	if j, ok := jobs[id]; ok {
+		j.Cancel()
+		delete(jobs, id)
+	}
+	mu.Unlock()
+	return bucket.Delete([]byte(id))
+}
+
+// List returns reminders of the user in the group ordered by the next time.
+func List(groupID, userID int64) []*Reminder {
+	var list []*Reminder
+	err := bucket.ForEach(func(_, v []byte) bool {
+		r := &Reminder{}
+		if json.Unmarshal(v, r) == nil && r.GroupID == groupID && r.UserID == userID {
+			list = append(list, r)
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("读取提醒失败: %v", err)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Next().Before(list[j].Next())
+	})
+	return list
+}
+
+// Next returns the next time of the reminder.
+func (r *Reminder) Next() time.Time {
+	mu.Lock()
+	j, ok := jobs[r.ID]
+	mu.Unlock()
+	if ok && !j.Next().IsZero() {
+		return j.Next()
+	}
+	return time.Unix(r.At, 0)
+}
+
+func (r *Reminder) String() string {
+	t := r.Next().Format("2006-01-02 15:04")
+	if r.Cron != "" {
+		t = fmt.Sprintf("%s (%s)", t, describeCron(r.Cron))
+	}
+	return t + " " + r.Content
+}
+
+// load 重启后重新安排所有提醒, 已过期的一次性提醒会立即发送
+func load() {
+	var list []*Reminder
+	err := bucket.ForEach(func(k, v []byte) bool {
+		r := &Reminder{}
+		if err := json.Unmarshal(v, r); err != nil {
+			log.Errorf("解析提醒 %s 失败: %v", k, err)
+			return true
+		}
+		list = append(list, r)
+		return true
+	})
+	if err != nil {
+		log.Errorf("读取提醒失败: %v", err)
+	}
+	for _, r := range list {
+		if err = schedule(r); err != nil {
+			log.Errorf("恢复提醒 %v 失败: %v", r.ID, err)
+		}
+	}
+}
+
+func schedule(r *Reminder) error {
+	o := &scheduler.Options{Name: "reminder:" + r.ID}
+	var (
+		j   *scheduler.Job
+		err error
+	)
+	if r.Cron != "" {
+		j, err = scheduler.Cron(r.Cron, func() { deliver(r) }, o)
+		if err != nil {
+			return err
+		}
+	} else {
+		j = scheduler.At(time.Unix(r.At, 0), func() {
+			deliver(r)
+			_ = Remove(r.ID)
+		}, o)
+	}
+	mu.Lock()
+	jobs[r.ID] = j
+	mu.Unlock()
+	return nil
+}
+
+func deliver(r *Reminder) {
+	if r.GroupID == 0 {
+		zero.SendPrivateMessage(r.UserID, "⏰ "+r.Content)
+		return
+	}
+	zero.SendGroupMessage(r.GroupID, message.New().At(r.UserID).Text(" ⏰ "+r.Content).Build())
+}
+
+func handleAdd(_ *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+	var cmd extension.CommandModel
+	_ = state.Parse(&cmd)
+	w, content, err := Parse(cmd.Args, time.Now())
+	switch {
+	case err == ErrNoTime:
+		zero.Send(event, "没有识别到提醒时间, 例如: 提醒 明天下午3点 开会")
+		return zero.FinishResponse
+	case err != nil:
+		zero.Send(event, "提醒时间有误: "+err.Error())
+		return zero.FinishResponse
+	case content == "":
+		zero.Send(event, "请输入提醒内容")
+		return zero.FinishResponse
+	}
+	if len(List(event.GroupID, event.UserID)) >= MaxPerUser {
+		zero.Send(event, fmt.Sprintf("最多只能添加 %d 个提醒", MaxPerUser))
+		return zero.FinishResponse
+	}
+	r := &Reminder{GroupID: event.GroupID, UserID: event.UserID, Content: content, At: w.At.Unix(), Cron: w.Cron}
+	if err = Add(r); err != nil {
+		zero.Send(event, "添加提醒失败: "+err.Error())
+		return zero.FinishResponse
+	}
+	zero.Send(event, "好的, 将在 "+r.String()+" 提醒你")
+	return zero.FinishResponse
+}
+
+func handleList(_ *zero.Matcher, event zero.Event, _ zero.State) zero.Response {
+	list := List(event.GroupID, event.UserID)
+	if len(list) == 0 {
+		zero.Send(event, "你还没有提醒")
+		return zero.FinishResponse
+	}
+	lines := make([]string, len(list))
+	for i, r := range list {
+		lines[i] = fmt.Sprintf("%d. %v", i+1, r)
+	}
+	zero.Send(event, strings.Join(lines, "\n"))
+	return zero.FinishResponse
+}
+
+func handleCancel(_ *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+	var cmd extension.CommandModel
+	_ = state.Parse(&cmd)
+	list := List(event.GroupID, event.UserID)
+	arg := strings.TrimSpace(cmd.Args)
+	if arg == "全部" || strings.EqualFold(arg, "all") {
+		for _, r := range list {
+			_ = Remove(r.ID)
+		}
+		zero.Send(event, fmt.Sprintf("已取消 %d 个提醒", len(list)))
+		return zero.FinishResponse
+	}
+	i, err := strconv.Atoi(arg)
+	if err != nil || i < 1 || i > len(list) {
+		zero.Send(event, "请输入提醒列表中的编号")
+		return zero.FinishResponse
+	}
+	if err = Remove(list[i-1].ID); err != nil {
+		zero.Send(event, "取消提醒失败: "+err.Error())
+		return zero.FinishResponse
+	}
+	zero.Send(event, "已取消提醒: "+list[i-1].Content)
+	return zero.FinishResponse
+}
+
+var weekdayNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// describeCron 描述 Parse 生成的 cron 表达式
+func describeCron(spec string) string {
+	f := strings.Fields(spec)
+	if len(f) != 5 {
+		return spec
+	}
+	switch {
+	case f[2] != "*":
+		return "每月" + f[2] + "号"
+	case f[4] == "1-5":
+		return "每个工作日"
+	case f[4] != "*":
+		d, _ := strconv.Atoi(f[4])
+		return "每周" + weekdayNames[d%7]
+	default:
+		return "每天"
+	}
+}
@@ -0,0 +1,20 @@
+package reminder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdd(t *testing.T) {
+	r := &Reminder{GroupID: 1, UserID: 2, Content: "交房租", Cron: "0 9 32 * *"}
+	assert.Error(t, Add(r))
+	assert.Empty(t, List(1, 2)) // cron 无效时不保存
+
+	r = &Reminder{GroupID: 1, UserID: 2, Content: "交房租", Cron: "0 9 1 * *"}
+	if assert.NoError(t, Add(r)) {
+		assert.Len(t, List(1, 2), 1)
+		assert.NoError(t, Remove(r.ID))
+	}
+	assert.Empty(t, List(1, 2))
+}
@@ -0,0 +1,224 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a job runs.
+type Schedule interface {
+	// Next returns the next activation time later than t, zero time means never.
+	Next(t time.Time) time.Time
+}
+
+// every 每隔固定时间执行一次
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cronSchedule 每个字段用位表示允许的值
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	loc                                   *time.Location
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dows = bounds{0, 6, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// 字段为 * 时设置的标记位, 用于区分日期与星期的匹配方式
+const starBit = 1 << 63
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression in the given location (nil means time.Local).
+//
+// The expression has 5 fields (minute hour day-of-month month day-of-week)
+// or 6 fields with a leading second field, each field supports
+// `*`, `a-b`, `*/n`, `a-b/n`, lists like `1,3,5`, and month/weekday names like `jan`/`mon`.
+// Descriptors `@yearly` `@monthly` `@weekly` `@daily` `@hourly` and `@every <duration>`
+// are also accepted, a leading `TZ=Asia/Shanghai` overrides the location.
+func ParseCron(spec string, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("cron: missing fields in %q", spec)
+		}
+		var err error
+		loc, err = time.LoadLocation(spec[strings.IndexByte(spec, '=')+1 : i])
+		if err != nil {
+			return nil, fmt.Errorf("cron: %v", err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("cron: %v", err)
+		}
+		if d <= 0 {
+			return nil, errors.New("cron: @every duration must be positive")
+		}
+		if d < time.Second {
+			d = time.Second
+		}
+		return every(d), nil
+	}
+	if d, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron: expected 5 or 6 fields, found %d in %q", len(fields), spec)
+	}
+	s := &cronSchedule{loc: loc}
+	var err error
+	for i, f := range []struct {
+		p *uint64
+		b bounds
+	}{
+		{&s.second, seconds}, {&s.minute, minutes}, {&s.hour, hours},
+		{&s.dom, doms}, {&s.month, months}, {&s.dow, dows},
+	} {
+		if *f.p, err = parseField(fields[i], f.b); err != nil {
+			return nil, err
+		}
+	}
+	if s.dow&(1<<7) != 0 { // 7 也表示星期日
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		rangeAndStep := strings.SplitN(expr, "/", 2)
+		lowAndHigh := strings.SplitN(rangeAndStep[0], "-", 2)
+		var (
+			start, end, step uint = 0, 0, 1
+			err              error
+		)
+		max := b.max
+		if b.max == 6 { // 允许星期字段使用 7
+			max = 7
+		}
+		if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+			if len(lowAndHigh) > 1 {
+				return 0, fmt.Errorf("cron: invalid range %q", expr)
+			}
+			start, end = b.min, b.max
+			if len(rangeAndStep) == 1 {
+				bits |= starBit
+			}
+		} else {
+			if start, err = parseValue(lowAndHigh[0], b); err != nil {
+				return 0, err
+			}
+			end = start
+			if len(lowAndHigh) > 1 {
+				if end, err = parseValue(lowAndHigh[1], b); err != nil {
+					return 0, err
+				}
+			} else if len(rangeAndStep) > 1 {
+				end = b.max
+			}
+		}
+		if len(rangeAndStep) > 1 {
+			n, err := strconv.ParseUint(rangeAndStep[1], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("cron: invalid step %q", expr)
+			}
+			step = uint(n)
+		}
+		if start < b.min || end > max || start > end {
+			return 0, fmt.Errorf("cron: %q out of range [%d, %d]", expr, b.min, b.max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("cron: invalid value %q", s)
+	}
+	return uint(v), nil
+}
+
+// Next returns the next time matching the schedule later than t.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	origin := t.Location()
+	t = t.In(s.loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, s.loc)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, s.loc)
+		case s.second&(1<<uint(t.Second())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, s.loc)
+		default:
+			return t.In(origin)
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches 日期与星期都有限制时满足其一即可, 与标准 cron 一致
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.dom&starBit != 0 || s.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,353 @@
+// Package scheduler provides cron and delayed jobs for plugins,
+// one-shot jobs can be persisted by extension/kv to survive restarts.
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/wdvxdr1123/ZeroBot/extension/kv"
+)
+
+// Handler handles a persisted job with the data passed to Persist.
+type Handler func(data []byte)
+
+// Options holds the optional parameters of a job.
+type Options struct {
+	// Name 任务名, 用于日志和列表展示
+	Name string
+	// Location 解析 cron 表达式使用的时区, 默认为 time.Local
+	Location *time.Location
+	// Jitter 每次执行前额外随机等待 [0, Jitter) 的时间
+	Jitter time.Duration
+	// AllowOverlap 是否允许上次执行未结束时开始新的执行, 默认跳过本次执行
+	AllowOverlap bool
+}
+
+// Job is a scheduled job.
+type Job struct {
+	id      string
+	name    string
+	spec    string
+	sched   Schedule  // 为 nil 时为一次性任务
+	at      time.Time // 一次性任务的执行时间
+	fn      func()
+	jitter  time.Duration
+	overlap bool
+	handler string // 持久化任务的处理函数名
+	data    []byte
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	next     time.Time
+	canceled bool
+	running  int32
+}
+
+// record 持久化的一次性任务
+type record struct {
+	Handler string    `json:"handler"`
+	At      time.Time `json:"at"`
+	Data    []byte    `json:"data"`
+}
+
+var (
+	// ErrUnknownHandler is returned by Persist when the handler is not registered.
+	ErrUnknownHandler = errors.New("scheduler: unknown handler")
+
+	bucket   = kv.New("scheduler")
+	lock     sync.RWMutex
+	jobs     = map[string]*Job{}
+	handlers = map[string]Handler{}
+	lastID   int64
+)
+
+// Cron adds a job running fn on the cron expression, see ParseCron for the syntax.
+func Cron(spec string, fn func(), o *Options) (*Job, error) {
+	var loc *time.Location
+	if o != nil {
+		loc = o.Location
+	}
+	s, err := ParseCron(spec, loc)
+	if err != nil {
+		return nil, err
+	}
+	j := newJob(spec, fn, o)
+	j.sched = s
+	return j.start(), nil
+}
+
+// Every adds a job running fn once every d.
+func Every(d time.Duration, fn func(), o *Options) *Job {
+	if d < time.Second {
+		d = time.Second
+	}
+	j := newJob("@every "+d.String(), fn, o)
+	j.sched = every(d)
+	return j.start()
+}
+
+// After adds a one-shot job running fn after d.
+func After(d time.Duration, fn func(), o *Options) *Job {
+	return At(time.Now().Add(d), fn, o)
+}
+
+// At adds a one-shot job running fn at t, it runs immediately if t has passed.
+func At(t time.Time, fn func(), o *Options) *Job {
+	j := newJob("@at "+t.Format("2006-01-02 15:04:05"), fn, o)
+	j.at = t
+	return j.start()
+}
+
+// Register registers a handler for persisted jobs,
+// and reschedules jobs persisted with the handler before restarting,
+// jobs already scheduled are not restored again.
+func Register(name string, handler Handler) {
+	var restored []*Job
+	lock.Lock()
+	handlers[name] = handler
+	err := bucket.ForEach(func(k, v []byte) bool {
+		var r record
+		if err := json.Unmarshal(v, &r); err != nil {
+			log.Errorf("解析定时任务 %s 失败: %v", k, err)
+			return true
+		}
+		if _, ok := jobs[string(k)]; !ok && r.Handler == name {
+			j := newPersisted(string(k), r)
+			jobs[j.id] = j
+			restored = append(restored, j)
+		}
+		return true
+	})
+	lock.Unlock()
+	if err != nil {
+		log.Errorf("读取定时任务失败: %v", err)
+	}
+	for _, j := range restored {
+		j.plan(time.Now())
+	}
+}
+
+// Persist adds a one-shot job which calls the registered handler with data at t,
+// the job is saved to kv and will be restored by Register after restarting.
+func Persist(handler string, t time.Time, data []byte) (*Job, error) {
+	lock.RLock()
+	_, ok := handlers[handler]
+	lock.RUnlock()
+	if !ok {
+		return nil, ErrUnknownHandler
+	}
+	r := record{Handler: handler, At: t, Data: data}
+	j := newPersisted(nextID(), r)
+	v, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	if err = bucket.Put([]byte(j.id), v); err != nil {
+		return nil, err
+	}
+	return j.start(), nil
+}
+
+// Lookup returns the job with the id.
+func Lookup(id string) (*Job, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	j, ok := jobs[id]
+	return j, ok
+}
+
+// Jobs returns all scheduled jobs ordered by the next activation time.
+func Jobs() []*Job {
+	lock.RLock()
+	list := make([]*Job, 0, len(jobs))
+	for _, j := range jobs {
+		list = append(list, j)
+	}
+	lock.RUnlock()
+	sort.Slice(list, func(i, k int) bool {
+		return list[i].Next().Before(list[k].Next())
+	})
+	return list
+}
+
+// Cancel cancels the job with the id, returns false if not found.
+func Cancel(id string) bool {
+	j, ok := Lookup(id)
+	if ok {
+		j.Cancel()
+	}
+	return ok
+}
+
+// ID returns the unique id of the job.
+func (j *Job) ID() string { return j.id }
+
+// Name returns the name of the job.
+func (j *Job) Name() string { return j.name }
+
+// Spec returns the schedule description of the job.
+func (j *Job) Spec() string { return j.spec }
+
+// Data returns the data of a persisted job.
+func (j *Job) Data() []byte { return append([]byte(nil), j.data...) }
+
+// Persistent reports whether the job is persisted.
+func (j *Job) Persistent() bool { return j.handler != "" }
+
+// Running reports whether the job is running.
+func (j *Job) Running() bool { return atomic.LoadInt32(&j.running) > 0 }
+
+// Next returns the next activation time, zero time if the job is finished.
+func (j *Job) Next() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.next
+}
+
+func (j *Job) String() string {
+	return fmt.Sprintf("%s(%s)", j.name, j.spec)
+}
+
+// Cancel stops the job, a running execution is not interrupted.
+func (j *Job) Cancel() {
+	j.mu.Lock()
+	j.canceled = true
+	j.next = time.Time{}
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	j.mu.Unlock()
+	j.remove()
+}
+
+func newJob(spec string, fn func(), o *Options) *Job {
+	j := &Job{id: nextID(), spec: spec, fn: fn}
+	if o != nil {
+		j.name = o.Name
+		j.jitter = o.Jitter
+		j.overlap = o.AllowOverlap
+	}
+	if j.name == "" {
+		j.name = j.id
+	}
+	return j
+}
+
+func newPersisted(id string, r record) *Job {
+	j := &Job{id: id, name: r.Handler, handler: r.Handler, at: r.At, data: r.Data}
+	j.spec = "@at " + r.At.Format("2006-01-02 15:04:05")
+	j.fn = func() {
+		lock.RLock()
+		handler := handlers[j.handler]
+		lock.RUnlock()
+		handler(j.Data())
+	}
+	return j
+}
+
+func (j *Job) start() *Job {
+	lock.Lock()
+	jobs[j.id] = j
+	lock.Unlock()
+	j.plan(time.Now())
+	return j
+}
+
+// plan 计算下次执行的时间并设置定时器
+func (j *Job) plan(after time.Time) {
+	var next time.Time
+	if j.sched == nil {
+		next = j.at
+	} else {
+		next = j.sched.Next(after)
+	}
+	if next.IsZero() {
+		j.remove()
+		return
+	}
+	delay := time.Until(next)
+	if j.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(j.jitter)))
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.canceled {
+		return
+	}
+	j.next = next
+	j.timer = time.AfterFunc(delay, j.run)
+}
+
+func (j *Job) run() {
+	j.mu.Lock()
+	if j.canceled {
+		j.mu.Unlock()
+		return
+	}
+	planned := j.next
+	j.mu.Unlock()
+	if j.sched != nil { // 先安排下次执行, 避免任务耗时影响下次执行的时间
+		now := time.Now()
+		if j.sched.Next(planned).After(now) {
+			now = planned // 按计划时间计算, 避免抖动累积
+		}
+		j.plan(now)
+	} else {
+		j.mu.Lock()
+		j.next = time.Time{}
+		j.mu.Unlock()
+	}
+	if atomic.AddInt32(&j.running, 1) > 1 && !j.overlap {
+		atomic.AddInt32(&j.running, -1)
+		log.Warnf("定时任务 %v 上次执行尚未结束, 跳过本次执行", j)
+		return
+	}
+	defer func() {
+		atomic.AddInt32(&j.running, -1)
+		if pa := recover(); pa != nil {
+			log.Errorf("定时任务 %v 执行出错: %v\n%v", j, pa, string(debug.Stack()))
+		}
+		if j.sched == nil {
+			j.remove()
+		}
+	}()
+	j.fn()
+}
+
+func (j *Job) remove() {
+	lock.Lock()
+	if jobs[j.id] == j {
+		delete(jobs, j.id)
+	}
+	lock.Unlock()
+	if j.Persistent() {
+		if err := bucket.Delete([]byte(j.id)); err != nil {
+			log.Errorf("删除定时任务 %v 失败: %v", j, err)
+		}
+	}
+}
+
+// nextID 生成递增的任务 ID, 重启后也不会重复
+func nextID() string {
+	for {
+		last := atomic.LoadInt64(&lastID)
+		id := time.Now().UnixNano()
+		if id <= last {
+			id = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastID, last, id) {
+			return strconv.FormatInt(id, 36)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	base := time.Date(2021, 3, 1, 8, 30, 0, 0, loc) // 星期一
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 9 * * *", time.Date(2021, 3, 1, 9, 0, 0, 0, loc)},
+		{"*/20 * * * *", time.Date(2021, 3, 1, 8, 40, 0, 0, loc)},
+		{"0 8 * * mon-fri", time.Date(2021, 3, 2, 8, 0, 0, 0, loc)},
+		{"0 0 1,15 * *", time.Date(2021, 3, 15, 0, 0, 0, 0, loc)},
+		{"0 0 13 * 5", time.Date(2021, 3, 5, 0, 0, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2021, 3, 7, 0, 0, 0, 0, loc)},
+		{"30 0 9 * * *", time.Date(2021, 3, 1, 9, 0, 30, 0, loc)},
+		{"@monthly", time.Date(2021, 4, 1, 0, 0, 0, 0, loc)},
+		{"@every 90m", base.Add(90 * time.Minute)},
+		{"TZ=UTC 0 0 * * *", time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.spec, loc)
+		if assert.NoError(t, err, tt.spec) {
+			assert.True(t, tt.next.Equal(s.Next(base)), "%s: %v", tt.spec, s.Next(base))
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * foo *"} {
+		_, err := ParseCron(spec, loc)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestJob(t *testing.T) {
+	done := make(chan struct{})
+	j := After(10*time.Millisecond, func() { close(done) }, &Options{Name: "test"})
+	_, ok := Lookup(j.ID())
+	assert.True(t, ok)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job not fired")
+	}
+	time.Sleep(10 * time.Millisecond)
+	_, ok = Lookup(j.ID())
+	assert.False(t, ok)
+
+	j = After(time.Hour, func() { t.Error("canceled job fired") }, nil)
+	assert.True(t, Cancel(j.ID()))
+	assert.True(t, j.Next().IsZero())
+	assert.False(t, Cancel(j.ID()))
+}
+
+func TestPersist(t *testing.T) {
+	_, err := Persist("unknown", time.Now(), nil)
+	assert.Equal(t, ErrUnknownHandler, err)
+
+	done := make(chan []byte, 1)
+	Register("test", func(data []byte) { done <- data })
+	j, err := Persist("test", time.Now().Add(10*time.Millisecond), []byte("hello"))
+	assert.NoError(t, err)
+	assert.True(t, j.Persistent())
+	select {
+	case data := <-done:
+		assert.Equal(t, "hello", string(data))
+	case <-time.After(time.Second):
+		t.Fatal("job not fired")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("restore", func([]byte) {})
+	j, err := Persist("restore", time.Now().Add(time.Hour), []byte("hello"))
+	assert.NoError(t, err)
+	defer Cancel(j.ID())
+
+	count := func() (n int) {
+		for _, job := range Jobs() {
+			if job.ID() == j.ID() {
+				n++
+			}
+		}
+		return
+	}
+	Register("restore", func([]byte) {})
+	restored, _ := Lookup(j.ID())
+	assert.True(t, restored == j) // 已安排的任务不会重复恢复
+
+	lock.Lock()
+	delete(jobs, j.ID()) // 模拟重启
+	lock.Unlock()
+	Register("restore", func([]byte) {})
+	Register("restore", func([]byte) {})
+	assert.Equal(t, 1, count())
+	restored, _ = Lookup(j.ID())
+	assert.Equal(t, "hello", string(restored.Data()))
+}
@@ -0,0 +1,211 @@
+// Package session provides conversation sessions persisted in the kv store,
+// so that pending dialogs can be resumed after the bot restarts.
+//
+// A session is keyed by bot, group and user, the next message of the user
+// in the same group(or private chat) will be sent to the handler registered
+// by the session's handler name.
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension/kv"
+)
+
+// Handler handles a message of the session, returns true to finish the session.
+type Handler func(s *Session, event zero.Event) (done bool)
+
+// Session is a pending conversation.
+type Session struct {
+	Handler string          `json:"handler"`  // 处理会话的 Handler 名
+	SelfID  int64           `json:"self_id"`  // 机器人账号
+	GroupID int64           `json:"group_id"` // 群号, 私聊时为 0
+	UserID  int64           `json:"user_id"`  // 用户账号
+	Data    json.RawMessage `json:"data"`     // 会话数据
+	Expire  int64           `json:"expire"`   // 过期时间戳, 为 0 时不会过期
+}
+
+var (
+	bucket   = kv.New("session")
+	handlers = map[string]Handler{}
+	mu       = sync.RWMutex{}
+	// keys 未完成会话的键, 避免每条消息都读取数据库
+	keys     map[string]struct{}
+	keysMu   = sync.RWMutex{}
+	keysOnce = sync.Once{}
+	// locks 按会话键分段的锁, 同一会话的消息依次处理
+	locks [64]sync.Mutex
+)
+
+func init() {
+	zero.OnMessage(pending).
+		SetBlock(true).
+		SetPriority(math.MinInt32). // 会话优先于其他 Matcher
+		Handle(resume)
+}
+
+// Register registers a handler with the name, handlers must be
+// registered before the bot runs to resume sessions after restart.
+func Register(name string, handler Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[name] = handler
+}
+
+// Start starts a session of the event's user, the next message of the
+// user will be handled by the handler. ttl <= 0 means never expire.
+func Start(handler string, event zero.Event, data interface{}, ttl time.Duration) (*Session, error) {
+	s := &Session{
+		Handler: handler,
+		SelfID:  selfID(&event),
+		GroupID: event.GroupID,
+		UserID:  event.UserID,
+	}
+	if ttl > 0 {
+		s.Expire = time.Now().Add(ttl).Unix()
+	}
+	return s, s.Save(data)
+}
+
+// Load loads the session of the user in the group.
+func Load(selfID, groupID, userID int64) (*Session, error) {
+	data, err := bucket.Get(key(selfID, groupID, userID))
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Bind unmarshals the session's data into v.
+func (s *Session) Bind(v interface{}) error {
+	return json.Unmarshal(s.Data, v)
+}
+
+// Save marshals v as the session's data and persists the session.
+func (s *Session) Save(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.Data = data
+	data, err = json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	k := key(s.SelfID, s.GroupID, s.UserID)
+	if err = bucket.Put(k, data); err != nil {
+		return err
+	}
+	loadKeys()
+	keysMu.Lock()
+	keys[string(k)] = struct{}{}
+	keysMu.Unlock()
+	return nil
+}
+
+// Finish removes the session.
+func (s *Session) Finish() error {
+	k := key(s.SelfID, s.GroupID, s.UserID)
+	loadKeys()
+	keysMu.Lock()
+	delete(keys, string(k))
+	keysMu.Unlock()
+	return bucket.Delete(k)
+}
+
+// loadKeys 从数据库加载未完成会话的键
+func loadKeys() {
+	keysOnce.Do(func() {
+		m := map[string]struct{}{}
+		_ = bucket.ForEach(func(k, _ []byte) bool {
+			m[string(k)] = struct{}{}
+			return true
+		})
+		keysMu.Lock()
+		keys = m
+		keysMu.Unlock()
+	})
+}
+
+// lock 返回会话键对应的锁
+func lock(k []byte) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write(k)
+	return &locks[h.Sum32()%uint32(len(locks))]
+}
+
+// pending 检查消息的发送者是否有未完成的会话
+func pending(event *zero.Event, state zero.State) bool {
+	id := selfID(event)
+	k := key(id, event.GroupID, event.UserID)
+	loadKeys()
+	keysMu.RLock()
+	_, ok := keys[string(k)]
+	keysMu.RUnlock()
+	if !ok {
+		return false
+	}
+	s, err := Load(id, event.GroupID, event.UserID)
+	if err != nil {
+		return false
+	}
+	if s.Expire != 0 && time.Now().Unix() > s.Expire {
+		_ = s.Finish()
+		return false
+	}
+	mu.RLock()
+	_, ok = handlers[s.Handler]
+	mu.RUnlock()
+	if ok {
+		state["session"] = s
+	}
+	return ok
+}
+
+func resume(_ *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+	s := state["session"].(*Session)
+	l := lock(key(s.SelfID, s.GroupID, s.UserID))
+	l.Lock()
+	defer l.Unlock()
+	s, err := Load(s.SelfID, s.GroupID, s.UserID) // 会话可能已被并发处理的消息修改
+	if err != nil {
+		return zero.FinishResponse
+	}
+	if conf := &zero.BotConfig.Session; conf.IsExitWord(&event) {
+		_ = s.Finish()
+		if conf.CancelReply != "" {
+			zero.Send(event, conf.CancelReply)
+		}
+		return zero.FinishResponse
+	}
+	mu.RLock()
+	handler, ok := handlers[s.Handler]
+	mu.RUnlock()
+	if !ok || handler(s, event) { // 没有对应的 Handler 时结束会话
+		_ = s.Finish()
+	}
+	return zero.FinishResponse
+}
+
+func selfID(event *zero.Event) int64 {
+	if id := event.RawEvent.Get("self_id"); id.Exists() {
+		return id.Int()
+	}
+	id, _ := strconv.ParseInt(zero.BotConfig.SelfID, 10, 64)
+	return id
+}
+
+func key(selfID, groupID, userID int64) []byte {
+	return []byte(fmt.Sprintf("%d:%d:%d", selfID, groupID, userID))
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+func TestSession(t *testing.T) {
+	zero.BotConfig.SelfID = "10"
+	defer func() { zero.BotConfig.SelfID = "" }()
+
+	var got []string
+	Register("count", func(s *Session, e zero.Event) bool {
+		var n int
+		_ = s.Bind(&n)
+		n++
+		got = append(got, e.RawMessage)
+		_ = s.Save(n)
+		return n >= 2
+	})
+	msg := func(userID int64, text string) *zero.Event {
+		return &zero.Event{GroupID: 1, UserID: userID, RawMessage: text, Message: message.Message{message.Text(text)}}
+	}
+	handle := func(e *zero.Event) bool {
+		state := zero.State{}
+		if !pending(e, state) {
+			return false
+		}
+		resume(nil, *e, state)
+		return true
+	}
+
+	s, err := Start("count", *msg(2, ""), 0, 0)
+	assert.NoError(t, err)
+	defer func() { _ = s.Finish() }()
+	loaded, err := Load(10, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, s, loaded)
+
+	assert.True(t, handle(msg(2, "a")))
+	assert.False(t, handle(msg(3, "a"))) // 其他用户没有会话
+	keysOnce, keys = sync.Once{}, nil    // 模拟重启后从数据库恢复
+	assert.True(t, handle(msg(2, "b")))
+	assert.False(t, handle(msg(2, "c"))) // 已结束
+	assert.Equal(t, []string{"a", "b"}, got)
+	_, err = Load(10, 1, 2)
+	assert.Error(t, err)
+
+	s, _ = Start("count", *msg(2, ""), 0, 0)
+	assert.True(t, handle(msg(2, "取消")))
+	assert.False(t, handle(msg(2, "d")))
+	assert.Equal(t, []string{"a", "b"}, got)
+
+	s, _ = Start("count", *msg(2, ""), 0, time.Minute)
+	s.Expire = time.Now().Add(-time.Second).Unix()
+	_ = s.Save(0)
+	assert.False(t, handle(msg(2, "e"))) // 已过期
+	_, err = Load(10, 1, 2)
+	assert.Error(t, err)
+
+	Register("gone", func(*Session, zero.Event) bool { return false })
+	s, _ = Start("gone", *msg(2, ""), 0, 0)
+	state := zero.State{}
+	assert.True(t, pending(msg(2, "f"), state))
+	mu.Lock()
+	delete(handlers, "gone")
+	mu.Unlock()
+	resume(nil, *msg(2, "f"), state) // Handler 已不存在时结束会话
+	_, err = Load(10, 1, 2)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+}
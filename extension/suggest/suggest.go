@@ -0,0 +1,214 @@
+// Package suggest provides a fallback matcher which suggests
+// similar commands when the user types an unknown command.
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/extension/manager"
+	"github.com/wdvxdr1123/ZeroBot/extension/rate"
+)
+
+// Options holds the optional parameters for the suggestion matcher.
+type Options struct {
+	// Priority 优先级, 默认为 math.MaxInt32, 即在所有 Matcher 之后匹配
+	Priority int
+	// Max 最多给出的建议数量, 默认为 3
+	Max int
+	// Interval 和 Burst 控制每个群(私聊时为每个用户)的回复频率,
+	// 默认每 20 秒恢复 1 次回复机会, 最多连续回复 3 次
+	Interval time.Duration
+	Burst    int
+	// DisableOnDefault 默认在群内关闭, 需要使用 Enable 开启
+	DisableOnDefault bool
+	// Pinyin 返回汉字的拼音, 设置后会将拼音相同或相近的命令也作为建议
+	Pinyin func(r rune) string
+}
+
+var m manager.Manager
+
+// Register registers the suggestion matcher to ZeroBot.
+func Register(o *Options) *zero.Matcher {
+	var opt Options
+	if o != nil {
+		opt = *o
+	}
+	if opt.Priority == 0 {
+		opt.Priority = math.MaxInt32
+	}
+	if opt.Max <= 0 {
+		opt.Max = 3
+	}
+	if opt.Interval <= 0 || opt.Burst <= 0 {
+		opt.Interval, opt.Burst = time.Minute/3, 3
+	}
+	m = manager.New("suggest", &manager.Options{DisableOnDefault: opt.DisableOnDefault})
+	zero.AddHook(m)
+	limit := rate.NewManager(opt.Interval, opt.Burst)
+	return zero.OnMessage(unknownCommand).
+		SetBlock(true).
+		SetPriority(opt.Priority).
+		Handle(func(_ *zero.Matcher, event zero.Event, state zero.State) zero.Response {
+			key := event.GroupID
+			if key == 0 {
+				key = event.UserID
+			}
+			if !limit.Load(key).Acquire() {
+				return zero.FinishResponse
+			}
+			suggestions := Suggest(state["command"].(string), opt.Max, opt.Pinyin)
+			if len(suggestions) == 0 {
+				return zero.FinishResponse
+			}
+			prefix := state["prefix"].(string)
+			zero.Send(event, "未知命令, 你是不是想找: "+prefix+strings.Join(suggestions, " "+prefix))
+			return zero.FinishResponse
+		})
+}
+
+// Enable enables the suggestion in the group.
+func Enable(groupID int64) {
+	if m != nil {
+		m.Enable(groupID)
+	}
+}
+
+// Disable disables the suggestion in the group.
+func Disable(groupID int64) {
+	if m != nil {
+		m.Disable(groupID)
+	}
+}
+
+// unknownCommand 检查消息是否为带有命令前缀但没有被任何命令匹配的消息
+func unknownCommand(event *zero.Event, state zero.State) bool {
+	if len(event.Message) == 0 || event.Message[0].Type != "text" {
+		return false
+	}
+	text := event.Message[0].Data["text"]
+	prefix, rest, ok := zero.TrimCommandPrefix(event.GroupID, text)
+	if !ok || prefix == "" {
+		return false
+	}
+	word := strings.Fields(rest)
+	if len(word) == 0 {
+		return false
+	}
+	if _, ok := zero.LookupCommand(event); ok {
+		return false
+	}
+	state["prefix"] = prefix
+	state["command"] = word[0]
+	return true
+}
+
+// Suggest returns at most max registered command names similar to the input.
+func Suggest(input string, max int, pinyin func(r rune) string) []string {
+	type candidate struct {
+		name  string
+		score float64
+	}
+	var (
+		candidates []candidate
+		seen       = map[string]bool{}
+	)
+	zero.ForEachMatcher(func(m *zero.Matcher) bool {
+		if m.Command == nil || m.Command.Name == "" {
+			return true
+		}
+		for _, name := range append([]string{m.Command.Name}, m.Command.Aliases...) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if score := similarity(input, name, pinyin); score >= 0.5 {
+				candidates = append(candidates, candidate{name: name, score: score})
+			}
+		}
+		return true
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > max {
+		candidates = candidates[:max]
+	}
+	ret := make([]string, len(candidates))
+	for i := range candidates {
+		ret[i] = candidates[i].name
+	}
+	return ret
+}
+
+// similarity 返回两个字符串的相似度, 范围为 [0, 1]
+func similarity(a, b string, pinyin func(r rune) string) float64 {
+	score := ratio([]rune(strings.ToLower(a)), []rune(strings.ToLower(b)))
+	if pinyin != nil {
+		if s := ratio([]rune(toPinyin(a, pinyin)), []rune(toPinyin(b, pinyin))); s > score {
+			score = s
+		}
+	}
+	return score
+}
+
+func toPinyin(s string, pinyin func(r rune) string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if p := pinyin(r); p != "" {
+			sb.WriteString(p)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func ratio(a, b []rune) float64 {
+	l := len(a)
+	if len(b) > l {
+		l = len(b)
+	}
+	if l == 0 {
+		return 1
+	}
+	return 1 - float64(distance(a, b))/float64(l)
+}
+
+// distance 计算两个字符串的编辑距离, 相邻字符交换视为一次编辑
+func distance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min(n ...int) int {
+	m := n[0]
+	for _, v := range n[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,42 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 1, distance([]rune("musci"), []rune("music")))
+	assert.Equal(t, 1, distance([]rune("点哥"), []rune("点歌")))
+	assert.Equal(t, 3, distance([]rune("abc"), []rune("")))
+}
+
+func TestSimilarity(t *testing.T) {
+	pinyin := func(r rune) string {
+		return map[rune]string{'点': "dian", '歌': "ge", '哥': "ge"}[r]
+	}
+	assert.Equal(t, 0.8, similarity("musci", "MUSIC", nil))
+	assert.Equal(t, 0.5, similarity("点哥", "点歌", nil))
+	assert.Equal(t, 1.0, similarity("点哥", "点歌", pinyin))
+}
+
+func TestUnknownCommand(t *testing.T) {
+	zero.BotConfig.CommandPrefix = "/"
+	defer func() { zero.BotConfig.CommandPrefix = "" }()
+	music := zero.OnCommand("music")
+	defer music.Delete()
+
+	event := func(text string) *zero.Event {
+		return &zero.Event{Message: message.Message{message.Text(text)}}
+	}
+	state := zero.State{}
+	assert.True(t, unknownCommand(event("／musci 晴天"), state)) // 全角前缀与 CommandRule 一致
+	assert.Equal(t, zero.State{"prefix": "/", "command": "musci"}, state)
+	assert.False(t, unknownCommand(event("/music 晴天"), zero.State{}))
+	assert.False(t, unknownCommand(event("musci"), zero.State{}))
+	assert.False(t, unknownCommand(event("/"), zero.State{}))
+}
@@ -0,0 +1,98 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// Set is a set of named templates, plugins declare the default templates
+// and operators override them from a file without recompiling.
+//
+//	var replies = template.NewSet()
+//	replies.Default("hello", "{at:sender} 你好 {user}")
+//	_ = replies.LoadFile("data/replies.json") // {"hello": "欢迎 {user} [CQ:face,id=14]"}
+//	msg, err := replies.Render("hello", &event, nil)
+type Set struct {
+	mu        sync.RWMutex
+	templates map[string]*Template
+}
+
+// NewSet returns an empty template set.
+func NewSet() *Set {
+	return &Set{templates: map[string]*Template{}}
+}
+
+// Default adds the template if it is not added yet, it panics if text can't be parsed.
+func (s *Set) Default(name, text string) *Set {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.templates[name]; !ok {
+		s.templates[name] = Must(Parse(name, text))
+	}
+	return s
+}
+
+// Add parses the text and adds or replaces the template.
+func (s *Set) Add(name, text string) error {
+	t, err := Parse(name, text)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.templates[name] = t
+	s.mu.Unlock()
+	return nil
+}
+
+// Load adds or replaces all templates, nothing is changed if any of them can't be parsed.
+func (s *Set) Load(texts map[string]string) error {
+	parsed := make(map[string]*Template, len(texts))
+	for name, text := range texts {
+		t, err := Parse(name, text)
+		if err != nil {
+			return err
+		}
+		parsed[name] = t
+	}
+	s.mu.Lock()
+	for name, t := range parsed {
+		s.templates[name] = t
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// LoadFile loads templates from a json file of name -> text.
+func (s *Set) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var texts map[string]string
+	if err = json.Unmarshal(data, &texts); err != nil {
+		return fmt.Errorf("load templates %s: %w", path, err)
+	}
+	return s.Load(texts)
+}
+
+// Lookup returns the template by name.
+func (s *Set) Lookup(name string) (*Template, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.templates[name]
+	return t, ok
+}
+
+// Render renders the named template with the event and data.
+func (s *Set) Render(name string, event *zero.Event, data map[string]interface{}) (message.Message, error) {
+	t, ok := s.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+	return t.Render(event, data)
+}
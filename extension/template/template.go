@@ -0,0 +1,343 @@
+// Package template provides message templates with placeholders and Go template logic,
+// templates are compiled once and rendered into message segments.
+//
+// A template is CQ-like text where `{name}` or `{kind:arg}` are placeholders
+// and `{{ }}` are Go template actions, for example
+//
+//	{at:sender} 你好 {user}, {{if .Data.count}}你已经签到了 {{.Data.count}} 天{{else}}欢迎{{end}} [CQ:face,id=14]
+//
+// Values printed by Go template actions are escaped and always rendered as text,
+// a message.Message or message.MessageSegment value is rendered as segments.
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// PlaceholderFunc renders the placeholder `{kind:arg}`.
+type PlaceholderFunc func(ctx *Context, arg string) (message.Message, error)
+
+// Context is the data of rendering a template, it is the dot of Go template actions.
+type Context struct {
+	Event *zero.Event            // 触发的事件, 可以为 nil
+	Data  map[string]interface{} // 模板数据
+
+	segments []message.Message // 渲染时插入的消息段
+}
+
+// Template is a compiled message template.
+type Template struct {
+	tmpl *template.Template
+}
+
+const (
+	escapeFunc      = "_zero_escape"
+	placeholderFunc = "_zero_placeholder"
+	// 插入消息段的标记, 使用 Unicode 私有区字符
+	markerStart = '\uE000'
+	markerEnd   = '\uE001'
+)
+
+var (
+	placeholderReg = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?::([^{}\s]*))?$`)
+	markerReg      = regexp.MustCompile(`\x{E000}(\d+)\x{E001}`)
+
+	placeholderLock sync.RWMutex
+	placeholders    = map[string]PlaceholderFunc{
+		"user":     userPlaceholder,
+		"user_id":  func(ctx *Context, _ string) (message.Message, error) { return text(ctx.event().UserID), nil },
+		"group_id": func(ctx *Context, _ string) (message.Message, error) { return text(ctx.event().GroupID), nil },
+		"at":       atPlaceholder,
+		"image":    imagePlaceholder,
+		"face": func(_ *Context, arg string) (message.Message, error) {
+			return message.Message{message.Face(arg)}, nil
+		},
+		"reply": func(ctx *Context, _ string) (message.Message, error) {
+			return message.Message{message.Reply(strconv.FormatInt(ctx.event().MessageID, 10))}, nil
+		},
+	}
+)
+
+// RegisterPlaceholder registers a placeholder `{kind}` or `{kind:arg}`,
+// placeholders must be registered before parsing templates using them.
+func RegisterPlaceholder(kind string, fn PlaceholderFunc) {
+	placeholderLock.Lock()
+	defer placeholderLock.Unlock()
+	placeholders[kind] = fn
+}
+
+func lookupPlaceholder(kind string) (PlaceholderFunc, bool) {
+	placeholderLock.RLock()
+	defer placeholderLock.RUnlock()
+	fn, ok := placeholders[kind]
+	return fn, ok
+}
+
+// Parse compiles the template text.
+func Parse(name, text string) (*Template, error) {
+	src, err := compile(text)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		escapeFunc:      escape,
+		placeholderFunc: placeholder,
+	}).Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			escapeNode(t.Tree, t.Tree.Root)
+		}
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Must panics if err is not nil.
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.tmpl.Name()
+}
+
+// Render renders the template with the event and data.
+func (t *Template) Render(event *zero.Event, data map[string]interface{}) (message.Message, error) {
+	return t.Execute(&Context{Event: event, Data: data})
+}
+
+// Execute renders the template with the context, ctx is not modified
+// so it can be shared by concurrent executions.
+func (t *Template) Execute(ctx *Context) (message.Message, error) {
+	c := &Context{Event: ctx.Event, Data: ctx.Data} // 每次渲染使用独立的消息段列表
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	msg := message.Message{}
+	for _, seg := range message.ParseMessageFromString(buf.String()) {
+		if seg.Type != "text" {
+			msg = append(msg, seg)
+			continue
+		}
+		text := seg.Data["text"]
+		last := 0
+		for _, loc := range markerReg.FindAllStringSubmatchIndex(text, -1) {
+			if loc[0] > last {
+				msg = append(msg, message.Text(text[last:loc[0]]))
+			}
+			i, err := strconv.Atoi(text[loc[2]:loc[3]])
+			if err != nil || i >= len(c.segments) {
+				return nil, fmt.Errorf("template %s: invalid segment marker %q", t.Name(), text[loc[0]:loc[1]])
+			}
+			msg = append(msg, c.segments[i]...)
+			last = loc[1]
+		}
+		if last < len(text) {
+			msg = append(msg, message.Text(text[last:]))
+		}
+	}
+	return msg.Normalize(), nil
+}
+
+// compile 将 {kind:arg} 形式的占位符转换为 Go 模板的函数调用
+func compile(text string) (string, error) {
+	if strings.ContainsAny(text, string([]rune{markerStart, markerEnd})) {
+		return "", fmt.Errorf("template contains reserved characters U+E000 or U+E001")
+	}
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], "{{") { // Go 模板, 原样保留
+			j := strings.Index(text[i+2:], "}}")
+			if j < 0 {
+				sb.WriteString(text[i:])
+				break
+			}
+			end := i + 2 + j + 2
+			sb.WriteString(text[i:end])
+			i = end
+			continue
+		}
+		if text[i] == '{' {
+			if j := strings.IndexByte(text[i+1:], '}'); j >= 0 {
+				if m := placeholderReg.FindStringSubmatch(text[i+1 : i+1+j]); m != nil {
+					if _, ok := lookupPlaceholder(m[1]); !ok && strings.Contains(m[0], ":") {
+						return "", fmt.Errorf("unknown placeholder %q", m[0])
+					}
+					fmt.Fprintf(&sb, "{{%s $ %s %s}}", placeholderFunc, strconv.Quote(m[1]), strconv.Quote(m[2]))
+					i += j + 2
+					continue
+				}
+			}
+		}
+		sb.WriteByte(text[i])
+		i++
+	}
+	return sb.String(), nil
+}
+
+// escapeNode 在每个输出值的 action 后追加转义函数, 与 html/template 的做法相同
+func escapeNode(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeNode(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || isPlaceholder(n.Pipe) {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args: []parse.Node{
+				parse.NewIdentifier(escapeFunc).SetTree(tree).SetPos(n.Pos),
+				&parse.VariableNode{NodeType: parse.NodeVariable, Pos: n.Pos, Ident: []string{"$"}},
+			},
+		})
+	case *parse.IfNode:
+		escapeNode(tree, n.List)
+		escapeNode(tree, n.ElseList)
+	case *parse.RangeNode:
+		escapeNode(tree, n.List)
+		escapeNode(tree, n.ElseList)
+	case *parse.WithNode:
+		escapeNode(tree, n.List)
+		escapeNode(tree, n.ElseList)
+	}
+}
+
+func isPlaceholder(pipe *parse.PipeNode) bool {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+	id, ok := pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && id.Ident == placeholderFunc
+}
+
+// escape 将 action 输出的值转换为文本, 消息与消息段则插入为消息段
+func escape(root interface{}, v interface{}) string {
+	ctx, _ := root.(*Context)
+	switch m := v.(type) {
+	case message.Message:
+		if ctx != nil {
+			return ctx.insert(m)
+		}
+	case message.MessageSegment:
+		if ctx != nil {
+			return ctx.insert(message.Message{m})
+		}
+	}
+	s := strings.Map(func(r rune) rune {
+		if r == markerStart || r == markerEnd {
+			return -1
+		}
+		return r
+	}, fmt.Sprint(v))
+	return message.EscapeCQText(s)
+}
+
+func placeholder(root interface{}, kind, arg string) (string, error) {
+	ctx, ok := root.(*Context)
+	if !ok {
+		return "", fmt.Errorf("placeholder {%s} used outside of the root template data", kind)
+	}
+	fn, ok := lookupPlaceholder(kind)
+	if !ok { // 未注册的占位符为模板数据
+		v, ok := ctx.Data[kind]
+		if !ok {
+			return "", fmt.Errorf("missing data %q", kind)
+		}
+		return escape(ctx, v), nil
+	}
+	msg, err := fn(ctx, arg)
+	if err != nil {
+		return "", err
+	}
+	return ctx.insert(msg), nil
+}
+
+// insert 保存消息段并返回其标记
+func (ctx *Context) insert(msg message.Message) string {
+	ctx.segments = append(ctx.segments, msg)
+	return string(markerStart) + strconv.Itoa(len(ctx.segments)-1) + string(markerEnd)
+}
+
+func (ctx *Context) event() *zero.Event {
+	if ctx.Event == nil {
+		return &zero.Event{}
+	}
+	return ctx.Event
+}
+
+func text(v interface{}) message.Message {
+	return message.Message{message.Text(fmt.Sprint(v))}
+}
+
+func userPlaceholder(ctx *Context, _ string) (message.Message, error) {
+	e := ctx.event()
+	if e.Sender != nil {
+		if e.Sender.Card != "" {
+			return text(e.Sender.Card), nil
+		}
+		if e.Sender.NickName != "" {
+			return text(e.Sender.NickName), nil
+		}
+	}
+	return text(e.UserID), nil
+}
+
+func atPlaceholder(ctx *Context, arg string) (message.Message, error) {
+	switch arg {
+	case "", "sender":
+		return message.Message{message.At(strconv.FormatInt(ctx.event().UserID, 10))}, nil
+	case "all":
+		return message.Message{message.AtAll()}, nil
+	}
+	if _, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return message.Message{message.At(arg)}, nil
+	}
+	v, ok := ctx.Data[arg]
+	if !ok {
+		return nil, fmt.Errorf("missing data %q", arg)
+	}
+	return message.Message{message.At(fmt.Sprint(v))}, nil
+}
+
+func imagePlaceholder(ctx *Context, arg string) (message.Message, error) {
+	v, ok := ctx.Data[arg]
+	if !ok {
+		return nil, fmt.Errorf("missing data %q", arg)
+	}
+	switch img := v.(type) {
+	case message.MessageSegment:
+		return message.Message{img}, nil
+	case []byte:
+		seg, err := message.ImageBytes(img, nil)
+		if err != nil {
+			return nil, err
+		}
+		return message.Message{seg}, nil
+	default:
+		return message.Message{message.Image(fmt.Sprint(img))}, nil
+	}
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	zero "github.com/wdvxdr1123/ZeroBot"
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+func testEvent() *zero.Event {
+	return &zero.Event{
+		MessageID: 42,
+		GroupID:   100,
+		UserID:    123,
+		Sender:    &zero.User{ID: 123, NickName: "Alice"},
+	}
+}
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl := Must(Parse("hello", "{reply}{at:sender} 你好 {user}, {{if .Data.count}}已签到 {{.Data.count}} 天{{else}}欢迎{{end}}[CQ:face,id=14]"))
+	msg, err := tmpl.Render(testEvent(), map[string]interface{}{"count": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, message.Message{
+		message.Reply("42"),
+		message.At("123"),
+		message.Text(" 你好 Alice, 已签到 3 天"),
+		message.Face("14"),
+	}, msg)
+
+	msg, err = tmpl.Render(testEvent(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, message.Text(" 你好 Alice, 欢迎"), msg[2])
+}
+
+func TestTemplate_Escape(t *testing.T) {
+	tmpl := Must(Parse("echo", "{name} said: {{.Data.text}}"))
+	msg, err := tmpl.Render(nil, map[string]interface{}{
+		"name": "[CQ:at,qq=all]",
+		"text": "a & b [CQ:face,id=1] \uE0000\uE001",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, message.Message{message.Text("[CQ:at,qq=all] said: a & b [CQ:face,id=1] 0")}, msg)
+}
+
+func TestTemplate_Segments(t *testing.T) {
+	tmpl := Must(Parse("segments", "{image:pic}{at:all}{at:target}{{range .Data.items}} {{.}}{{end}}"))
+	msg, err := tmpl.Render(testEvent(), map[string]interface{}{
+		"pic":    "https://example.com/a.png",
+		"target": int64(456),
+		"items":  []interface{}{"x", message.Face("1"), message.Message{message.At("789")}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, message.Message{
+		message.Image("https://example.com/a.png"),
+		message.AtAll(),
+		message.At("456"),
+		message.Text(" x "),
+		message.Face("1"),
+		message.Text(" "),
+		message.At("789"),
+	}, msg)
+}
+
+func TestParse(t *testing.T) {
+	RegisterPlaceholder("greet", func(_ *Context, arg string) (message.Message, error) {
+		return message.Message{message.Text("hi " + arg)}, nil
+	})
+	msg, err := Must(Parse("greet", "{greet:bob} { not a placeholder } {{`{user}`}}")).Render(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, message.Message{message.Text("hi bob { not a placeholder } {user}")}, msg)
+
+	_, err = Parse("unknown", "{unknown:arg}")
+	assert.Error(t, err)
+	_, err = Parse("bad", "{{if}}")
+	assert.Error(t, err)
+	_, err = Must(Parse("missing", "{image:pic}")).Render(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestSet(t *testing.T) {
+	s := NewSet().Default("hello", "你好 {user}")
+	msg, err := s.Render("hello", testEvent(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "你好 Alice", msg.ExtractPlainText())
+
+	assert.Error(t, s.Load(map[string]string{"hello": "{{end}}"}))
+	assert.NoError(t, s.Load(map[string]string{"hello": "欢迎 {user_id}"}))
+	s.Default("hello", "ignored")
+	msg, err = s.Render("hello", testEvent(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "欢迎 123", msg.ExtractPlainText())
+
+	_, err = s.Render("nope", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestTemplate_Marker(t *testing.T) {
+	_, err := Parse("marker", "\uE0000\uE001")
+	assert.Error(t, err) // 保留的标记字符
+	msg, err := Must(Parse("marker", "{{.Data.text}}")).Render(nil, map[string]interface{}{"text": "\uE0009\uE001"})
+	assert.NoError(t, err)
+	assert.Equal(t, message.Message{message.Text("9")}, msg)
+
+	ctx := &Context{Event: testEvent()}
+	tmpl := Must(Parse("shared", "{at:sender}"))
+	done := make(chan message.Message)
+	for i := 0; i < 2; i++ {
+		go func() {
+			msg, _ := tmpl.Execute(ctx)
+			done <- msg
+		}()
+	}
+	assert.Equal(t, message.Message{message.At("123")}, <-done)
+	assert.Equal(t, message.Message{message.At("123")}, <-done)
+	assert.Nil(t, ctx.segments)
+}
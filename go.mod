@@ -1,5 +1,7 @@
 module github.com/wdvxdr1123/ZeroBot
 
+// 最低支持 go 1.15; message/cqcode_fuzz_test.go 中的模糊测试需要 go 1.18 及以上版本,
+// 低版本会通过构建标签跳过该文件
 go 1.15
 
 require (
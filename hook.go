@@ -23,6 +23,11 @@ func (m *multiHooker) Hook() Rule {
 	}
 }
 
+// Hookers returns the hookers combined by the multi hooker.
+func (m *multiHooker) Hookers() []Hooker {
+	return m.hookers
+}
+
 func MultiHooker(h ...Hooker) Hooker {
 	return &multiHooker{hookers: h}
 }
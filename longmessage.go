@@ -0,0 +1,67 @@
+package zero
+
+import (
+	"strconv"
+
+	"github.com/wdvxdr1123/ZeroBot/message"
+)
+
+// 超长消息的处理方式
+const (
+	LongMessageSplit   = "split"   // 拆分为多条消息发送
+	LongMessageForward = "forward" // 以合并转发发送
+)
+
+// LongMessageConfig 是超长消息的处理方式, Mode 为空时不处理
+type LongMessageConfig struct {
+	Mode        string `json:"mode"`         // split 或 forward
+	MaxLength   int    `json:"max_length"`   // 每条消息的最大字符数, 默认为 message.DefaultMaxLength
+	MaxSegments int    `json:"max_segments"` // 每条消息的最大消息段数, 默认为 message.DefaultMaxSegments
+}
+
+// toMessage 将发送的消息转换为 message.Message
+func toMessage(msg interface{}) (message.Message, bool) {
+	switch m := msg.(type) {
+	case string:
+		return message.ParseMessageFromString(m), true
+	case message.Message:
+		return m, true
+	case message.MessageSegment:
+		return message.Message{m}, true
+	default:
+		return nil, false
+	}
+}
+
+// sendLongMessage 按 BotConfig.LongMessage 处理超长消息后发送, 拆分发送时返回第一条消息的 ID,
+// forward 为 nil 时不支持合并转发, 拆分发送
+func sendLongMessage(msg interface{}, send func(msg interface{}) (int64, error), forward func(forward message.ForwardMessage) (int64, error)) (int64, error) {
+	c := &BotConfig.LongMessage
+	if c.Mode == "" {
+		return send(msg)
+	}
+	opt := &message.SplitOptions{MaxLength: c.MaxLength, MaxSegments: c.MaxSegments}
+	m, ok := toMessage(msg)
+	if !ok || !m.Exceeds(opt) {
+		return send(msg)
+	}
+	if c.Mode == LongMessageForward && forward != nil {
+		name := "ZeroBot"
+		if len(BotConfig.NickName) > 0 {
+			name = BotConfig.NickName[0]
+		}
+		uin, _ := strconv.ParseInt(BotConfig.SelfID, 10, 64)
+		return forward(message.NewForward().Pages(name, uin, m, opt).Build())
+	}
+	var first int64
+	for i, part := range message.Split(m, opt) {
+		id, err := send(part)
+		if err != nil {
+			return first, err
+		}
+		if i == 0 {
+			first = id
+		}
+	}
+	return first, nil
+}
@@ -1,9 +1,14 @@
 package zero
 
 import (
+	"fmt"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type (
@@ -11,19 +16,27 @@ type (
 	// Rule filter the event
 	Rule    func(event *Event, state State) bool
 	Handler func(matcher *Matcher, event Event, state State) Response
+	// Middleware 包装 Handler, 可以在 Handler 执行前后进行额外处理或跳过 Handler
+	Middleware func(next Handler) Handler
 )
 
 const (
 	SuccessResponse Response = iota
+	// RejectResponse 等待触发者在同一会话中的下一条消息, 该消息需要重新满足
+	// 当前 Matcher 的所有 Rule, 然后使用累积的 State 重新运行 Handler
 	RejectResponse
 	FinishResponse
+	// PauseResponse 等待触发者在同一会话中的下一条消息, 不检查 Rule,
+	// 直接使用累积的 State 重新运行 Handler
+	PauseResponse
 )
 
 // Matcher 是 ZeroBot 匹配和处理事件的最小单元
 type Matcher struct {
 	// Temp 是否为临时Matcher，临时 Matcher 匹配一次后就会删除当前 Matcher
 	Temp bool
-	// Block 是否阻断后续 Matcher，为 true 时当前Matcher匹配成功后，后续Matcher不参与匹配
+	// Block 是否阻断后续 Matcher，为 true 时当前Matcher匹配成功后，后续Matcher不参与匹配,
+	// Handler 中修改 matcher.Block 只对当前事件生效
 	Block bool
 	// Priority 优先级，越小优先级越高
 	Priority int
@@ -37,22 +50,95 @@ type Matcher struct {
 	Rules []Rule
 	// Handler 处理事件的函数
 	Handler Handler
+	// Middlewares 作用于 Handler 的中间件, Reject 或 Pause 后重新运行 Handler 时不会经过中间件
+	Middlewares []Middleware
+	// Command 命令的帮助信息, 非命令 Matcher 为 nil
+	Command *CommandInfo
+	// MaxRetry Reject 或 Pause 的最大次数, 为 0 时不限制, 达到后回复 BotConfig.Session.RetryReply
+	MaxRetry int
+	// Timeout Reject 或 Pause 等待下一条消息的时间, 为 0 时使用 BotConfig.Session.Timeout
+	Timeout time.Duration
+
+	// 创建该 Matcher 的源文件
+	file string
+	// 该 Matcher 所使用的 Hooker
+	hooker Hooker
+	// hooker 添加到 Rules 中的 Rule
+	hook Rule
+	// 已经 Reject 或 Pause 的次数
+	retry int
+	// 是否为 Reject 或 Pause 产生的会话 Matcher
+	session bool
+	// 注册在 matcherList 中的原始 Matcher, 由 copy 设置
+	origin *Matcher
+}
+
+// CommandInfo 命令的帮助信息
+type CommandInfo struct {
+	// Name 命令名
+	Name string
+	// Aliases 命令别名
+	Aliases []string
+	// Usage 用法
+	Usage string
+	// Description 说明
+	Description string
+	// Examples 示例
+	Examples []string
+	// Permission 使用权限, 为 nil 时所有人均可使用
+	Permission Rule
+}
+
+// sessionKey 标识某个 Matcher 在某一会话中由 Reject 或 Pause 产生的会话 Matcher
+type sessionKey struct {
+	origin  *Matcher
+	groupID int64
+	userID  int64
 }
 
 var (
 	// 所有主匹配器列表
 	matcherList = make([]*Matcher, 0)
+	// 进行中的会话 Matcher, 会话期间原始 Matcher 不再被该会话的消息触发
+	sessions = map[sessionKey]*Matcher{}
+	// matcherList 中有命令名的 Matcher, 随 matcherList 更新
+	commandList []*Matcher
 	// Matcher 修改读写锁
 	matcherLock = sync.RWMutex{}
+	// ZeroBot 源码所在目录
+	zeroDir = func() string {
+		_, file, _, _ := runtime.Caller(0)
+		return filepath.Dir(file)
+	}()
 )
 
 // State store the context of a matcher.
 type State map[string]interface{}
 
 func sortMatcher() {
-	sort.Slice(matcherList, func(i, j int) bool { // 按优先级排序
+	sort.SliceStable(matcherList, func(i, j int) bool { // 按优先级排序, 同优先级保持添加顺序
 		return matcherList[i].Priority < matcherList[j].Priority
 	})
+	updateCommands()
+}
+
+// updateCommands 更新 commandList, 调用时需持有 matcherLock
+func updateCommands() {
+	commandList = commandList[:0:0]
+	for _, m := range matcherList {
+		if m.Command != nil && m.Command.Name != "" {
+			commandList = append(commandList, m)
+		}
+	}
+}
+
+// setCommand 设置命令信息并更新 commandList
+func (m *Matcher) setCommand(info *CommandInfo) *Matcher {
+	matcherLock.Lock()
+	defer matcherLock.Unlock()
+	m.Command = info
+	updateCommands()
+	return m
 }
 
 // SetBlock 设置是否阻断后面的 Matcher 触发
@@ -61,6 +147,24 @@ func (m *Matcher) SetBlock(block bool) *Matcher {
 	return m
 }
 
+// Use 添加中间件, 先添加的中间件在外层
+func (m *Matcher) Use(middlewares ...Middleware) *Matcher {
+	m.Middlewares = append(m.Middlewares, middlewares...)
+	return m
+}
+
+// SetMaxRetry 设置 Reject 或 Pause 的最大次数
+func (m *Matcher) SetMaxRetry(maxRetry int) *Matcher {
+	m.MaxRetry = maxRetry
+	return m
+}
+
+// SetTimeout 设置 Reject 或 Pause 等待下一条消息的时间
+func (m *Matcher) SetTimeout(timeout time.Duration) *Matcher {
+	m.Timeout = timeout
+	return m
+}
+
 // SetPriority 设置当前 Matcher 优先级
 func (m *Matcher) SetPriority(priority int) *Matcher {
 	matcherLock.Lock()
@@ -70,34 +174,114 @@ func (m *Matcher) SetPriority(priority int) *Matcher {
 	return m
 }
 
+// SetUsage 设置命令用法
+func (m *Matcher) SetUsage(usage string) *Matcher {
+	m.commandInfo().Usage = usage
+	return m
+}
+
+// SetDescription 设置命令说明
+func (m *Matcher) SetDescription(description string) *Matcher {
+	m.commandInfo().Description = description
+	return m
+}
+
+// SetExamples 设置命令示例
+func (m *Matcher) SetExamples(examples ...string) *Matcher {
+	m.commandInfo().Examples = examples
+	return m
+}
+
+// SetPermission 设置命令使用权限, 该 Rule 同时会作为匹配规则
+func (m *Matcher) SetPermission(permission Rule) *Matcher {
+	matcherLock.Lock()
+	defer matcherLock.Unlock()
+	m.Rules = append(m.Rules[:len(m.Rules):len(m.Rules)], permission)
+	m.commandInfo().Permission = permission
+	return m
+}
+
+func (m *Matcher) commandInfo() *CommandInfo {
+	if m.Command == nil {
+		m.Command = &CommandInfo{}
+	}
+	return m.Command
+}
+
+// Plugin 返回该 Matcher 所属的插件信息
+func (m *Matcher) Plugin() (PluginInfo, bool) {
+	if plugin, ok := lookupPlugin(m.file); ok {
+		return plugin.GetPluginInfo(), true
+	}
+	return PluginInfo{}, false
+}
+
+// String 返回 Matcher 的描述
+func (m *Matcher) String() string {
+	return fmt.Sprintf("Matcher{Priority: %v, Block: %v, Rules: [%v]}", m.Priority, m.Block, joinRules(m.Rules))
+}
+
+// Hooker 返回该 Matcher 所使用的 Hooker, 没有时返回 nil
+func (m *Matcher) Hooker() Hooker {
+	return m.hooker
+}
+
 // On 添加新的主匹配器
 func On(type_ string, rules ...Rule) *Matcher {
 	var i = 1
-	var hooked Rule
+	var hooker Hooker
 	for {
 		_, file, _, ok := runtime.Caller(i)
 		if !ok {
 			break
 		}
-		if hooker, ok := hooks[file]; ok { // find hook -> add hook
-			hooked = hooker.Hook()
+		if h, ok := hooks[file]; ok { // find hook -> add hook
+			hooker = h
 			break
 		}
 		i++
 	}
+	var hook Rule
+	if hooker != nil {
+		hook = hooker.Hook()
+		rules = append(rules, hook)
+	}
 	var matcher = &Matcher{
-		State: map[string]interface{}{},
-		Type:  Type(type_),
-		Rules: func() []Rule {
-			if hooked != nil {
-				return append(rules, hooked)
-			}
-			return rules
-		}(),
+		State:  map[string]interface{}{},
+		Type:   Type(type_),
+		Rules:  rules,
+		file:   callerFile(),
+		hooker: hooker,
+		hook:   hook,
 	}
 	return StoreMatcher(matcher)
 }
 
+// callerFile 返回调用栈中第一个不属于 ZeroBot 的源文件
+func callerFile() string {
+	for i := 1; ; i++ {
+		_, file, _, ok := runtime.Caller(i)
+		if !ok {
+			return ""
+		}
+		if filepath.Dir(file) != zeroDir {
+			return file
+		}
+	}
+}
+
+// ForEachMatcher iterates through main matchers.
+func ForEachMatcher(iterator func(matcher *Matcher) bool) {
+	matcherLock.RLock()
+	list := append([]*Matcher(nil), matcherList...)
+	matcherLock.RUnlock()
+	for _, matcher := range list {
+		if !iterator(matcher) {
+			return
+		}
+	}
+}
+
 // StoreMatcher store a matcher to matcher list.
 func StoreMatcher(m *Matcher) *Matcher {
 	matcherLock.Lock()
@@ -116,11 +300,40 @@ func StoreTempMatcher(m *Matcher) *Matcher {
 
 // Delete remove the matcher from list
 func (m *Matcher) Delete() {
+	m.remove()
+}
+
+// remove 删除 Matcher 并返回其是否在列表中
+func (m *Matcher) remove() bool {
 	matcherLock.Lock()
 	defer matcherLock.Unlock()
 	for i, matcher := range matcherList {
 		if m == matcher {
 			matcherList = append(matcherList[:i], matcherList[i+1:]...)
+			updateCommands()
+			if !m.session {
+				m.removeSessions()
+			} else if k := (sessionKey{m.origin, m.Event.GroupID, m.Event.UserID}); sessions[k] == m {
+				delete(sessions, k)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// removeSessions 删除 m 产生的会话 Matcher, 调用时需持有 matcherLock
+func (m *Matcher) removeSessions() {
+	for k, session := range sessions {
+		if k.origin != m {
+			continue
+		}
+		delete(sessions, k)
+		for i, matcher := range matcherList {
+			if matcher == session {
+				matcherList = append(matcherList[:i], matcherList[i+1:]...)
+				break
+			}
 		}
 	}
 }
@@ -130,52 +343,162 @@ func (m *Matcher) run(event Event) {
 	if m.Handler == nil {
 		return
 	}
-	switch m.Handler(m, event, m.State) {
-	case RejectResponse:
-		StoreTempMatcher(&Matcher{
-			Type:     Type("message"),
-			Block:    m.Block,
-			Priority: m.Priority,
-			State:    m.State,
-			Rules: []Rule{
-				CheckUser(event.UserID),
-			},
-			Handler: m.Handler,
-		})
+	conf := &BotConfig.Session
+	if m.session && conf.IsExitWord(&event) {
+		if conf.CancelReply != "" {
+			Send(event, conf.CancelReply)
+		}
 		return
 	}
+	handler := m.Handler
+	for i := len(m.Middlewares) - 1; i >= 0; i-- {
+		handler = m.Middlewares[i](handler)
+	}
+	switch rsp := handler(m, event, m.State); rsp {
+	case RejectResponse, PauseResponse:
+		if m.MaxRetry > 0 && m.retry >= m.MaxRetry {
+			log.Debugf("%v 已达到最大重试次数 %v", m, m.MaxRetry)
+			if conf.RetryReply != "" {
+				Send(event, conf.RetryReply)
+			}
+			return
+		}
+		m.wait(event, rsp == RejectResponse)
+	}
 }
 
-// Get ..
-func (m *Matcher) Get(prompt string) string {
-	ch := make(chan string)
-	event := m.Event
-	Send(*event, prompt)
-	StoreTempMatcher(&Matcher{
-		Priority: m.Priority,
+// wait 在原始 Matcher 之前添加一个等待触发者在同一会话中下一条消息的临时 Matcher,
+// 超时后删除该 Matcher 并回复 BotConfig.Session.TimeoutReply
+func (m *Matcher) wait(event Event, checkRules bool) {
+	var (
+		typ     = Type("message")
+		session = SessionRule(&event)
+		rules   = []Rule{session}
+		conf    = &BotConfig.Session
+	)
+	if checkRules {
+		typ = m.Type
+		rules = []Rule{session, func(e *Event, state State) bool {
+			if conf.IsExitWord(e) { // 退出关键词不需要满足原有 Rule
+				return true
+			}
+			for _, rule := range m.Rules {
+				if !rule(e, state) {
+					return false
+				}
+			}
+			return true
+		}}
+	}
+	temp := &Matcher{
+		Temp:     true,
+		Type:     typ,
 		Block:    m.Block,
-		Type:     Type("message"),
-		State:    map[string]interface{}{},
-		Rules: []Rule{
-			CheckUser(event.UserID),
-		},
-		Handler: func(_ *Matcher, ev Event, _ State) Response {
-			ch <- ev.RawMessage
-			return SuccessResponse
-		},
+		Priority: m.Priority,
+		State:    m.State,
+		Event:    &event,
+		Rules:    rules,
+		Handler:  m.Handler,
+		MaxRetry: m.MaxRetry,
+		Timeout:  m.Timeout,
+		file:     m.file,
+		hooker:   m.hooker,
+		retry:    m.retry + 1,
+		session:  true,
+		origin:   m.origin,
+	}
+	matcherLock.Lock()
+	i := len(matcherList)
+	for j, matcher := range matcherList {
+		if matcher == m.origin {
+			i = j
+			break
+		}
+	}
+	list := make([]*Matcher, 0, len(matcherList)+1) // 不修改正在被 processEvent 遍历的列表
+	list = append(append(append(list, matcherList[:i]...), temp), matcherList[i:]...)
+	matcherList = list
+	sortMatcher()
+	sessions[sessionKey{m.origin, event.GroupID, event.UserID}] = temp
+	matcherLock.Unlock()
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = conf.timeout()
+	}
+	reply := conf.TimeoutReply
+	time.AfterFunc(timeout, func() {
+		if temp.remove() && reply != "" {
+			Send(event, reply)
+		}
 	})
-	return <-ch
+}
+
+// Reject 发送 prompt 并返回 RejectResponse
+func (m *Matcher) Reject(prompt interface{}) Response {
+	Send(*m.Event, prompt)
+	return RejectResponse
+}
+
+// Pause 发送 prompt 并返回 PauseResponse
+func (m *Matcher) Pause(prompt interface{}) Response {
+	Send(*m.Event, prompt)
+	return PauseResponse
+}
+
+// Get 发送 prompt 并返回触发者的下一条消息, 会话被取消时返回空字符串
+//
+// 只有设置了 BotConfig.Session.Timeout 时才会超时, 超时返回空字符串;
+// 如需单独指定超时时间或区分超时和取消, 请使用 GetWithTimeout
+func (m *Matcher) Get(prompt string) string {
+	Send(*m.Event, prompt)
+	ctx, cancel := BotConfig.Session.context()
+	defer cancel()
+	e, _ := m.receive(ctx)
+	return e.RawMessage
+}
+
+// eligible 检查事件是否满足 Matcher 的类型和所有 Rule, 不修改 Matcher 的 State
+//
+// Hooker 的 Rule 可能有副作用(如限流会消耗令牌), 只在真正匹配时执行, 这里跳过
+func (m *Matcher) eligible(event *Event) bool {
+	if !m.Type(event, nil) {
+		return false
+	}
+	state := copyState(m.State)
+	for _, rule := range m.Rules {
+		if m.hook != nil && ruleKey(rule) == ruleKey(m.hook) {
+			continue
+		}
+		if !rule(event, state) {
+			return false
+		}
+	}
+	return true
 }
 
 func (m *Matcher) copy() *Matcher {
+	origin := m.origin
+	if origin == nil {
+		origin = m
+	}
 	return &Matcher{
-		State:    copyState(m.State),
-		Type:     m.Type,
-		Rules:    m.Rules,
-		Block:    m.Block,
-		Priority: m.Priority,
-		Handler:  m.Handler,
-		Temp:     m.Temp,
+		State:       copyState(m.State),
+		Type:        m.Type,
+		Rules:       m.Rules,
+		Block:       m.Block,
+		Priority:    m.Priority,
+		Handler:     m.Handler,
+		Temp:        m.Temp,
+		Middlewares: m.Middlewares,
+		Command:     m.Command,
+		MaxRetry:    m.MaxRetry,
+		Timeout:     m.Timeout,
+		file:        m.file,
+		hooker:      m.hooker,
+		hook:        m.hook,
+		retry:       m.retry,
+		session:     m.session,
+		origin:      origin,
 	}
 }
 
@@ -226,7 +549,8 @@ func OnSuffix(suffix string, rules ...Rule) *Matcher {
 
 // OnCommand 命令触发器
 func OnCommand(commands string, rules ...Rule) *Matcher {
-	return OnMessage(append([]Rule{CommandRule(commands)}, rules...)...)
+	return OnMessage(append([]Rule{CommandRule(commands)}, rules...)...).
+		setCommand(&CommandInfo{Name: commands})
 }
 
 // OnRegex 正则触发器
@@ -256,7 +580,11 @@ func OnKeywordGroup(keywords []string, rules ...Rule) *Matcher {
 
 // OnCommandGroup 命令触发器组
 func OnCommandGroup(commands []string, rules ...Rule) *Matcher {
-	return OnMessage(append([]Rule{CommandRule(commands...)}, rules...)...)
+	m := OnMessage(append([]Rule{CommandRule(commands...)}, rules...)...)
+	if len(commands) > 0 {
+		m.setCommand(&CommandInfo{Name: commands[0], Aliases: commands[1:]})
+	}
+	return m
 }
 
 // OnPrefixGroup 前缀触发器组
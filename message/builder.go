@@ -0,0 +1,134 @@
+package message
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Builder 链式构造消息, 相邻的文本会自动合并
+//
+//	message.New().Reply(id).At(uid).Text("hi ").Face(14).Build()
+type Builder struct {
+	msg Message
+}
+
+// New 返回一个新的消息构造器
+func New() *Builder {
+	return &Builder{msg: Message{}}
+}
+
+// Segment 添加消息段
+func (b *Builder) Segment(segs ...MessageSegment) *Builder {
+	for _, seg := range segs {
+		if seg.Type == "text" {
+			b.Text(seg.Data["text"])
+			continue
+		}
+		b.msg = append(b.msg, seg)
+	}
+	return b
+}
+
+// Message 添加消息中的所有消息段
+func (b *Builder) Message(m Message) *Builder {
+	return b.Segment(m...)
+}
+
+// Text 添加文本, 与前一个文本合并
+func (b *Builder) Text(text string) *Builder {
+	if text == "" {
+		return b
+	}
+	if n := len(b.msg); n > 0 && b.msg[n-1].Type == "text" {
+		b.msg[n-1] = Text(b.msg[n-1].Data["text"] + text)
+		return b
+	}
+	b.msg = append(b.msg, Text(text))
+	return b
+}
+
+// Textf 添加格式化的文本
+func (b *Builder) Textf(format string, a ...interface{}) *Builder {
+	return b.Text(fmt.Sprintf(format, a...))
+}
+
+// Line 添加文本并换行
+func (b *Builder) Line(text string) *Builder {
+	return b.Text(text + "\n")
+}
+
+// Linef 添加格式化的文本并换行
+func (b *Builder) Linef(format string, a ...interface{}) *Builder {
+	return b.Text(fmt.Sprintf(format, a...) + "\n")
+}
+
+// Newline 换行
+func (b *Builder) Newline() *Builder {
+	return b.Text("\n")
+}
+
+// At @某人
+func (b *Builder) At(userID int64) *Builder {
+	return b.Segment(At(strconv.FormatInt(userID, 10)))
+}
+
+// AtAll @全体成员
+func (b *Builder) AtAll() *Builder {
+	return b.Segment(AtAll())
+}
+
+// Reply 回复消息
+func (b *Builder) Reply(messageID int64) *Builder {
+	return b.Segment(Reply(strconv.FormatInt(messageID, 10)))
+}
+
+// Face QQ表情
+func (b *Builder) Face(id int) *Builder {
+	return b.Segment(Face(strconv.Itoa(id)))
+}
+
+// Image 图片
+func (b *Builder) Image(file string) *Builder {
+	return b.Segment(Image(file))
+}
+
+// Record 语音
+func (b *Builder) Record(file string) *Builder {
+	return b.Segment(Record(file))
+}
+
+// Poke 戳一戳
+func (b *Builder) Poke(userID int64) *Builder {
+	return b.Segment(Poke(strconv.FormatInt(userID, 10)))
+}
+
+// If cond 为 true 时才执行 f
+func (b *Builder) If(cond bool, f func(b *Builder)) *Builder {
+	if cond {
+		f(b)
+	}
+	return b
+}
+
+// When cond 为 true 时才添加消息段
+func (b *Builder) When(cond bool, segs ...MessageSegment) *Builder {
+	if cond {
+		b.Segment(segs...)
+	}
+	return b
+}
+
+// Len 返回消息段的数量
+func (b *Builder) Len() int {
+	return len(b.msg)
+}
+
+// Build 返回构造的消息, 之后对 Builder 的修改不会影响返回的消息
+func (b *Builder) Build() Message {
+	return append(Message{}, b.msg...)
+}
+
+// String 返回消息的 CQ 码
+func (b *Builder) String() string {
+	return b.msg.CQString()
+}
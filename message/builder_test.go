@@ -0,0 +1,18 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	b := New().Reply(1).At(10001).Text("hi ").Textf("%d", 2).Face(14).
+		Line("").Segment(Text("a")).
+		If(false, func(b *Builder) { b.Text("x") }).
+		When(true, Text("b")).Newline()
+	m := b.Build()
+	assert.Equal(t, Message{Reply("1"), At("10001"), Text("hi 2"), Face("14"), Text("\nab\n")}, m)
+	b.Text("c")
+	assert.Equal(t, Text("\nab\n"), m[4])
+}
@@ -1,18 +1,15 @@
 package message
 
 import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+
 	"github.com/tidwall/gjson"
-	"regexp"
 )
 
 // Modified from https://github.com/catsworld/qq-bot-api
 
-var (
-	matchReg = regexp.MustCompile(`\[CQ:\w+?.*?]`)
-	typeReg  = regexp.MustCompile(`\[CQ:(\w+)`)
-	paramReg = regexp.MustCompile(`,([\w\-.]+?)=([^,\]]+)`)
-)
-
 // ParseMessage parses msg, which might have 2 types, string or array,
 // depending on the configuration of cqhttp, to a Message.
 // msg is the value of key "message" of the data unmarshalled from the
@@ -54,34 +51,80 @@ func ParseMessageFromArray(msgs gjson.Result) Message {
 // msg is the value of key "message" of the data unmarshalled from the
 // API response JSON.
 // ParseMessageFromString cq信息转为字符串
+//
+// 不完整或格式错误的 CQ 码会被当作纯文本
 func ParseMessageFromString(str string) Message {
 	var m = Message{}
-	i := matchReg.FindAllStringSubmatchIndex(str, -1)
-	si := 0
-	for _, idx := range i {
-		if idx[0] > si {
-			text := str[si:idx[0]]
-			m = append(m, Text(UnescapeCQText(text)))
+	textStart := 0 // 尚未加入消息的文本的起始位置
+	for i := 0; i < len(str); {
+		j := strings.Index(str[i:], "[CQ:")
+		if j < 0 {
+			break
 		}
-		code := str[idx[0]:idx[1]]
-		si = idx[1]
-		t := typeReg.FindAllStringSubmatch(code, -1)[0][1]
-		ps := paramReg.FindAllStringSubmatch(code, -1)
-		d := make(map[string]string)
-		for _, p := range ps {
-			d[p[1]] = UnescapeCQCodeText(p[2])
+		j += i
+		seg, end, ok := parseCQCode(str, j)
+		if !ok {
+			i = j + 1
+			continue
 		}
-		m = append(m, MessageSegment{
-			Type: t,
-			Data: d,
-		})
+		if j > textStart {
+			m = append(m, Text(UnescapeCQText(str[textStart:j])))
+		}
+		m = append(m, seg)
+		i, textStart = end, end
 	}
-	if si != len(str) {
-		m = append(m, Text(str[si:]))
+	if textStart < len(str) {
+		m = append(m, Text(UnescapeCQText(str[textStart:])))
 	}
 	return m
 }
 
+// parseCQCode 解析 str[start:] 开头的 CQ 码, 返回 CQ 码的结束位置
+func parseCQCode(str string, start int) (seg MessageSegment, end int, ok bool) {
+	i := start + len("[CQ:")
+	j := scanName(str, i)
+	if j == i {
+		return
+	}
+	seg.Type = str[i:j]
+	seg.Data = map[string]string{}
+	for i = j; i < len(str); {
+		switch str[i] {
+		case ']':
+			return seg, i + 1, true
+		case ',':
+			i++
+			j = scanName(str, i)
+			if j == i || j == len(str) || str[j] != '=' {
+				return
+			}
+			key := str[i:j]
+			i = j + 1
+			for j = i; j < len(str) && str[j] != ',' && str[j] != ']'; j++ {
+				if str[j] == '[' { // 参数中的 [ 必须转义
+					return
+				}
+			}
+			seg.Data[key] = UnescapeCQCodeText(str[i:j])
+			i = j
+		default:
+			return
+		}
+	}
+	return
+}
+
+// scanName 返回从 i 开始由字母, 数字, '_', '-', '.' 组成的名称的结束位置
+func scanName(str string, i int) int {
+	for ; i < len(str); i++ {
+		c := str[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '-' || c == '.') {
+			break
+		}
+	}
+	return i
+}
+
 // CQString returns the CQEncoded string. All media in the message will be converted
 // to its CQCode.
 // CQString 解码cq字符串
@@ -107,3 +150,56 @@ func (m Message) ExtractPlainText() string {
 	}
 	return msg
 }
+
+// Equal 判断两个消息是否逐段相同
+func (m Message) Equal(other Message) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for i := range m {
+		if !m[i].Equal(other[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize 返回规范化后的消息: 合并相邻的文本, 去除空文本以及消息首尾的空白
+func (m Message) Normalize() Message {
+	var msg = Message{}
+	for _, seg := range m {
+		if seg.Type != "text" {
+			msg = append(msg, seg)
+			continue
+		}
+		if seg.Data["text"] == "" {
+			continue
+		}
+		if n := len(msg); n > 0 && msg[n-1].Type == "text" {
+			msg[n-1] = Text(msg[n-1].Data["text"] + seg.Data["text"])
+			continue
+		}
+		msg = append(msg, Text(seg.Data["text"]))
+	}
+	if len(msg) > 0 && msg[0].Type == "text" {
+		msg[0] = Text(strings.TrimLeftFunc(msg[0].Data["text"], unicode.IsSpace))
+	}
+	if n := len(msg); n > 0 && msg[n-1].Type == "text" {
+		msg[n-1] = Text(strings.TrimRightFunc(msg[n-1].Data["text"], unicode.IsSpace))
+	}
+	// 去除首尾空白后可能出现空文本
+	if len(msg) > 0 && msg[0].Data["text"] == "" && msg[0].Type == "text" {
+		msg = msg[1:]
+	}
+	if n := len(msg); n > 0 && msg[n-1].Data["text"] == "" && msg[n-1].Type == "text" {
+		msg = msg[:n-1]
+	}
+	return msg
+}
+
+// Hash 返回规范化后消息的哈希值, 可用于消息去重
+func (m Message) Hash() uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(m.Normalize().CQString()))
+	return h.Sum64()
+}
@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package message
+
+import (
+	"testing"
+)
+
+// FuzzParseMessageFromString 需要 go 1.18 及以上版本, 运行: go test -fuzz=FuzzParseMessageFromString ./message
+func FuzzParseMessageFromString(f *testing.F) {
+	for _, s := range []string{
+		"hello",
+		"[CQ:face]",
+		"[CQ:image,file=,url=http://x/y?a=1&amp;b=2]c&amp;d",
+		"[CQ:share,title=a&#44;b&#91;c&#93;]",
+		"[[CQ:face,id=1][CQ:at,qq=1",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		m := ParseMessageFromString(s)
+		again := ParseMessageFromString(m.CQString())
+		if len(m) != len(again) {
+			t.Fatalf("round trip of %q: %v != %v", s, m, again)
+		}
+		for i := range m {
+			if m[i].Type != again[i].Type || len(m[i].Data) != len(again[i].Data) {
+				t.Fatalf("round trip of %q: %v != %v", s, m[i], again[i])
+			}
+			for k, v := range m[i].Data {
+				if again[i].Data[k] != v {
+					t.Fatalf("round trip of %q: %v != %v", s, m[i], again[i])
+				}
+			}
+		}
+	})
+}
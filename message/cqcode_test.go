@@ -0,0 +1,120 @@
+package message
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessageFromString(t *testing.T) {
+	tests := []struct {
+		str  string
+		want Message
+	}{
+		{"hello", Message{Text("hello")}},
+		{"[CQ:face]", Message{{Type: "face", Data: map[string]string{}}}},
+		{"[CQ:face,id=123]哈哈", Message{Face("123"), Text("哈哈")}},
+		{
+			"a&#91;b[CQ:image,file=,url=http://x/y?a=1&amp;b=2]c&amp;d",
+			Message{Text("a[b"), {Type: "image", Data: map[string]string{"file": "", "url": "http://x/y?a=1&b=2"}}, Text("c&d")},
+		},
+		{"[CQ:share,title=a&#44;b&#91;c&#93;]", Message{{Type: "share", Data: map[string]string{"title": "a,b[c]"}}}},
+		{"[CQ:at,qq=1][CQ:at,qq=2]", Message{At("1"), At("2")}},
+		{"[CQ:at,qq=1", Message{Text("[CQ:at,qq=1")}},
+		{"[CQ:]x", Message{Text("[CQ:]x")}},
+		{"[CQ:at,qq]", Message{Text("[CQ:at,qq]")}},
+		{"[CQ:at qq=1]", Message{Text("[CQ:at qq=1]")}},
+		{"[[CQ:face,id=1]", Message{Text("["), Face("1")}},
+		{"[CQ:face,id=[CQ:face,id=2]", Message{Text("[CQ:face,id="), Face("2")}},
+		{"", Message{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseMessageFromString(tt.str), tt.str)
+	}
+}
+
+func TestCQStringRoundTrip(t *testing.T) {
+	m := Message{
+		Text("[a]&,b"),
+		{Type: "image", Data: map[string]string{"file": "x,y]&[", "url": ""}},
+		Text("&#91;"),
+		{Type: "face", Data: map[string]string{}},
+	}
+	assert.Equal(t, m, ParseMessageFromString(m.CQString()))
+}
+
+func TestCQCodeOrder(t *testing.T) {
+	seg := CustomMusic("qq", "http://a", "http://b", "title")
+	want := "[CQ:music,type=custom,sub_type=qq,url=http://a,audio=http://b,title=title]"
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, want, seg.CQCode())
+	}
+	seg = MessageSegment{Type: "unknown", Data: map[string]string{"b": "2", "c": "3", "a": "1"}}
+	assert.Equal(t, "[CQ:unknown,a=1,b=2,c=3]", seg.CQCode())
+	seg = MessageSegment{Type: "at", Data: map[string]string{"z": "1", "qq": "2"}}
+	assert.Equal(t, "[CQ:at,qq=2,z=1]", seg.CQCode())
+}
+
+func TestMessageNormalize(t *testing.T) {
+	m := Message{Text("  "), Text(" a"), Text(""), Text("b"), Face("1"), Text("c"), Text("d \n"), Text(" ")}
+	n := m.Normalize()
+	assert.Equal(t, Message{Text("ab"), Face("1"), Text("cd")}, n)
+	assert.True(t, n.Equal(Message{Text("ab"), Face("1"), Text("cd")}))
+	assert.False(t, n.Equal(m))
+	assert.Equal(t, m.Hash(), n.Hash())
+	assert.NotEqual(t, m.Hash(), Message{Text("ab"), Face("2"), Text("cd")}.Hash())
+	assert.True(t, MessageSegment{Type: "face"}.Equal(MessageSegment{Type: "face", Data: map[string]string{}}))
+}
+
+// 以下为旧的基于正则的实现, 用于对比性能
+var (
+	matchReg = regexp.MustCompile(`\[CQ:\w+?.*?]`)
+	typeReg  = regexp.MustCompile(`\[CQ:(\w+)`)
+	paramReg = regexp.MustCompile(`,([\w\-.]+?)=([^,\]]+)`)
+)
+
+func parseMessageFromStringRegex(str string) Message {
+	var m = Message{}
+	i := matchReg.FindAllStringSubmatchIndex(str, -1)
+	si := 0
+	for _, idx := range i {
+		if idx[0] > si {
+			text := str[si:idx[0]]
+			m = append(m, Text(UnescapeCQText(text)))
+		}
+		code := str[idx[0]:idx[1]]
+		si = idx[1]
+		t := typeReg.FindAllStringSubmatch(code, -1)[0][1]
+		ps := paramReg.FindAllStringSubmatch(code, -1)
+		d := make(map[string]string)
+		for _, p := range ps {
+			d[p[1]] = UnescapeCQCodeText(p[2])
+		}
+		m = append(m, MessageSegment{
+			Type: t,
+			Data: d,
+		})
+	}
+	if si != len(str) {
+		m = append(m, Text(str[si:]))
+	}
+	return m
+}
+
+const benchMessage = "[CQ:reply,id=123456][CQ:at,qq=10001] 你好, 这是一条测试消息[CQ:face,id=178]" +
+	"[CQ:image,file=abcdef.image,url=https://example.com/a?b=1&amp;c=2]后面还有一些文字"
+
+func BenchmarkParseMessageFromString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseMessageFromString(benchMessage)
+	}
+}
+
+func BenchmarkParseMessageFromStringRegex(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parseMessageFromStringRegex(benchMessage)
+	}
+}
@@ -0,0 +1,80 @@
+package message
+
+import (
+	"strconv"
+)
+
+// ForwardNode 合并转发节点, 与 CustomNode 不同, 自定义节点的内容以数组形式发送
+// https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#%E5%90%88%E5%B9%B6%E8%BD%AC%E5%8F%91%E6%B6%88%E6%81%AF%E8%8A%82%E7%82%B9
+type ForwardNode struct {
+	Type string          `json:"type"` // 总是为 node
+	Data ForwardNodeData `json:"data"`
+}
+
+// ForwardNodeData 合并转发节点的数据, ID 不为空时引用已有的消息
+type ForwardNodeData struct {
+	ID      string      `json:"id,omitempty"`      // 转发的消息 ID
+	Name    string      `json:"name,omitempty"`    // 自定义节点的发送者名称
+	Uin     string      `json:"uin,omitempty"`     // 自定义节点的发送者账号
+	Content interface{} `json:"content,omitempty"` // 自定义节点的内容, 为 Message 或嵌套的 ForwardMessage
+}
+
+// ForwardMessage 合并转发消息
+type ForwardMessage []ForwardNode
+
+// ForwardBuilder 链式构造合并转发消息
+//
+//	message.NewForward().Message(id).Custom("bot", 10001, message.Message{message.Text("hi")}).Build()
+type ForwardBuilder struct {
+	nodes ForwardMessage
+}
+
+// NewForward 返回一个新的合并转发消息构造器
+func NewForward() *ForwardBuilder {
+	return &ForwardBuilder{nodes: ForwardMessage{}}
+}
+
+// Message 添加已有的消息
+func (f *ForwardBuilder) Message(messageID int64) *ForwardBuilder {
+	f.nodes = append(f.nodes, ForwardNode{
+		Type: "node",
+		Data: ForwardNodeData{ID: strconv.FormatInt(messageID, 10)},
+	})
+	return f
+}
+
+// Custom 添加自定义消息
+func (f *ForwardBuilder) Custom(name string, uin int64, content Message) *ForwardBuilder {
+	f.nodes = append(f.nodes, ForwardNode{
+		Type: "node",
+		Data: ForwardNodeData{Name: name, Uin: strconv.FormatInt(uin, 10), Content: content},
+	})
+	return f
+}
+
+// Nested 添加嵌套的合并转发
+func (f *ForwardBuilder) Nested(name string, uin int64, inner *ForwardBuilder) *ForwardBuilder {
+	f.nodes = append(f.nodes, ForwardNode{
+		Type: "node",
+		Data: ForwardNodeData{Name: name, Uin: strconv.FormatInt(uin, 10), Content: inner.Build()},
+	})
+	return f
+}
+
+// Pages 将过长的内容按 Split 拆分为多个自定义消息
+func (f *ForwardBuilder) Pages(name string, uin int64, content Message, o *SplitOptions) *ForwardBuilder {
+	for _, page := range Split(content, o) {
+		f.Custom(name, uin, page)
+	}
+	return f
+}
+
+// Len 返回节点的数量
+func (f *ForwardBuilder) Len() int {
+	return len(f.nodes)
+}
+
+// Build 返回构造的合并转发消息
+func (f *ForwardBuilder) Build() ForwardMessage {
+	return append(ForwardMessage{}, f.nodes...)
+}
@@ -0,0 +1,26 @@
+package message
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForwardBuilder(t *testing.T) {
+	inner := NewForward().Custom("b", 2, Message{Text("inner")})
+	f := NewForward().Message(123).
+		Custom("a", 1, Message{Text("hi"), Face("1")}).
+		Nested("a", 1, inner).
+		Pages("a", 1, Message{Text("0123456789abc")}, &SplitOptions{MaxLength: 10}).
+		Build()
+	assert.Len(t, f, 5)
+	data, err := json.Marshal(f[:3])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type":"node","data":{"id":"123"}},
+		{"type":"node","data":{"name":"a","uin":"1","content":[{"type":"text","data":{"text":"hi"}},{"type":"face","data":{"id":"1"}}]}},
+		{"type":"node","data":{"name":"a","uin":"1","content":[{"type":"node","data":{"name":"b","uin":"2","content":[{"type":"text","data":{"text":"inner"}}]}}]}}
+	]`, string(data))
+	assert.Equal(t, Message{Text("abc")}, f[4].Data.Content)
+}
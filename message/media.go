@@ -0,0 +1,154 @@
+package message
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// MaxImageSize 图片的最大字节数
+	MaxImageSize int64 = 30 << 20
+	// MaxRecordSize 语音的最大字节数
+	MaxRecordSize int64 = 10 << 20
+)
+
+var (
+	// ErrTooLarge is returned when the media exceeds the max size.
+	ErrTooLarge = errors.New("message: media too large")
+	// ErrUnsupportedType is returned when the media type is not supported.
+	ErrUnsupportedType = errors.New("message: unsupported media type")
+)
+
+// ImageBytes 由图片数据生成 base64:// 图片
+func ImageBytes(data []byte, o *ImageOptions) (MessageSegment, error) {
+	if err := checkMedia(data, int64(len(data)), MaxImageSize, isImage); err != nil {
+		return MessageSegment{}, err
+	}
+	return ImageWithOptions(base64URI(data), o), nil
+}
+
+// ImageReader 读取图片数据生成 base64:// 图片
+func ImageReader(r io.Reader, o *ImageOptions) (MessageSegment, error) {
+	data, err := readAll(r, MaxImageSize)
+	if err != nil {
+		return MessageSegment{}, err
+	}
+	return ImageBytes(data, o)
+}
+
+// ImageFile 由本地图片文件生成 file:/// 图片
+func ImageFile(path string, o *ImageOptions) (MessageSegment, error) {
+	uri, err := fileURI(path, MaxImageSize, isImage)
+	if err != nil {
+		return MessageSegment{}, err
+	}
+	return ImageWithOptions(uri, o), nil
+}
+
+// RecordBytes 由语音数据生成 base64:// 语音
+func RecordBytes(data []byte, o *RecordOptions) (MessageSegment, error) {
+	if err := checkMedia(data, int64(len(data)), MaxRecordSize, isAudio); err != nil {
+		return MessageSegment{}, err
+	}
+	return RecordWithOptions(base64URI(data), o), nil
+}
+
+// RecordReader 读取语音数据生成 base64:// 语音
+func RecordReader(r io.Reader, o *RecordOptions) (MessageSegment, error) {
+	data, err := readAll(r, MaxRecordSize)
+	if err != nil {
+		return MessageSegment{}, err
+	}
+	return RecordBytes(data, o)
+}
+
+// RecordFile 由本地语音文件生成 file:/// 语音
+func RecordFile(path string, o *RecordOptions) (MessageSegment, error) {
+	uri, err := fileURI(path, MaxRecordSize, isAudio)
+	if err != nil {
+		return MessageSegment{}, err
+	}
+	return RecordWithOptions(uri, o), nil
+}
+
+// DetectMediaType 识别媒体数据的 MIME 类型, 在 http.DetectContentType 的基础上支持 amr 与 silk
+func DetectMediaType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("#!AMR")):
+		return "audio/amr"
+	case bytes.HasPrefix(data, []byte("#!SILK_V3")), bytes.HasPrefix(data, []byte("\x02#!SILK_V3")):
+		return "audio/silk"
+	}
+	return http.DetectContentType(data)
+}
+
+func isImage(mime string) bool {
+	return strings.HasPrefix(mime, "image/")
+}
+
+func isAudio(mime string) bool {
+	switch {
+	case strings.HasPrefix(mime, "audio/"), mime == "application/ogg", mime == "video/mp4", mime == "video/webm":
+		return true
+	}
+	return false
+}
+
+func checkMedia(data []byte, size, max int64, valid func(mime string) bool) error {
+	if size > max {
+		return fmt.Errorf("%w: %d bytes > %d bytes", ErrTooLarge, size, max)
+	}
+	if mime := DetectMediaType(data); !valid(mime) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, mime)
+	}
+	return nil
+}
+
+func readAll(r io.Reader, max int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, max)
+	}
+	return data, nil
+}
+
+func base64URI(data []byte) string {
+	return "base64://" + base64.StdEncoding.EncodeToString(data)
+}
+
+func fileURI(path string, max int64, valid func(mime string) bool) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	head := make([]byte, 512) // http.DetectContentType 最多使用 512 字节
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if err = checkMedia(head[:n], info.Size(), max, valid); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
@@ -0,0 +1,43 @@
+package message
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMedia(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	seg, err := ImageBytes(png, &ImageOptions{Type: "flash"})
+	assert.NoError(t, err)
+	assert.Equal(t, "base64://"+base64.StdEncoding.EncodeToString(png), seg.Data["file"])
+	assert.Equal(t, "flash", seg.Data["type"])
+
+	_, err = ImageReader(strings.NewReader("hello"), nil)
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+	max := MaxImageSize
+	MaxImageSize = 4
+	_, err = ImageBytes(png, nil)
+	assert.True(t, errors.Is(err, ErrTooLarge))
+	MaxImageSize = max
+
+	dir, err := ioutil.TempDir("", "media")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a #1.png")
+	assert.NoError(t, ioutil.WriteFile(path, png, 0o644))
+	seg, err = ImageFile(path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(dir)+"/a%20%231.png", seg.Data["file"])
+
+	_, err = RecordBytes([]byte("#!AMR\n\x00"), nil)
+	assert.NoError(t, err)
+	_, err = RecordFile(path, nil)
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+}
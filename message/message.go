@@ -1,6 +1,8 @@
 package message
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 
 	jsoniter "github.com/json-iterator/go"
@@ -30,10 +32,7 @@ func EscapeCQText(str string) string {
 // UnescapeCQText unescapes special characters in a non-media plain message.
 // UnescapeCQText cq码反解析
 func UnescapeCQText(str string) string {
-	str = strings.Replace(str, "&#93;", "]", -1)
-	str = strings.Replace(str, "&#91;", "[", -1)
-	str = strings.Replace(str, "&amp;", "&", -1)
-	return str
+	return unescape(str, false)
 }
 
 // EscapeCQCodeText escapes special characters in a cqcode value.
@@ -51,20 +50,127 @@ func EscapeCQCodeText(str string) string {
 // https://github.com/howmanybots/onebot/blob/master/v11/specs/message/string.md#%E8%BD%AC%E4%B9%89
 // UnescapeCQText cq码反解析
 func UnescapeCQCodeText(str string) string {
-	str = strings.Replace(str, "&#44;", ",", -1)
-	str = strings.Replace(str, "&#93;", "]", -1)
-	str = strings.Replace(str, "&#91;", "[", -1)
-	str = strings.Replace(str, "&amp;", "&", -1)
-	return str
+	return unescape(str, true)
+}
+
+// unescape 单次遍历反转义, 没有需要反转义的字符时不分配内存
+func unescape(str string, comma bool) string {
+	i := strings.IndexByte(str, '&')
+	if i < 0 {
+		return str
+	}
+	var sb strings.Builder
+	sb.Grow(len(str))
+	sb.WriteString(str[:i])
+	for i < len(str) {
+		if str[i] == '&' {
+			rest := str[i:]
+			switch {
+			case strings.HasPrefix(rest, "&amp;"):
+				sb.WriteByte('&')
+				i += len("&amp;")
+				continue
+			case strings.HasPrefix(rest, "&#91;"):
+				sb.WriteByte('[')
+				i += len("&#91;")
+				continue
+			case strings.HasPrefix(rest, "&#93;"):
+				sb.WriteByte(']')
+				i += len("&#93;")
+				continue
+			case comma && strings.HasPrefix(rest, "&#44;"):
+				sb.WriteByte(',')
+				i += len("&#44;")
+				continue
+			}
+		}
+		sb.WriteByte(str[i])
+		i++
+	}
+	return sb.String()
 }
 
-// CQCode 将数组消息转换为CQ码
+// paramOrder 已知消息类型的参数顺序, 其余参数按字典序排在后面
+var paramOrder = map[string][]string{
+	"face":      {"id"},
+	"image":     {"file", "type", "subType", "url", "cache", "proxy", "timeout", "id", "c"},
+	"record":    {"file", "magic", "url", "cache", "proxy", "timeout"},
+	"video":     {"file", "cover", "url", "cache", "proxy", "timeout", "c"},
+	"at":        {"qq", "name"},
+	"share":     {"url", "title", "content", "image"},
+	"contact":   {"type", "id"},
+	"location":  {"lat", "lon", "title", "content"},
+	"music":     {"type", "id", "sub_type", "url", "audio", "title", "content", "image"},
+	"reply":     {"id", "text", "qq", "time", "seq"},
+	"forward":   {"id"},
+	"node":      {"id", "name", "uin", "content", "seq"},
+	"xml":       {"data", "resid"},
+	"json":      {"data", "resid"},
+	"poke":      {"qq"},
+	"gift":      {"qq", "id"},
+	"tts":       {"text"},
+	"anonymous": {"ignore"},
+	"redbag":    {"title"},
+	"cardimage": {"file", "minwidth", "minheight", "maxwidth", "maxheight", "source", "icon"},
+}
+
+// keys 返回按固定顺序排列的参数名
+func (m MessageSegment) keys() []string {
+	keys := make([]string, 0, len(m.Data))
+	order := paramOrder[m.Type]
+	for _, k := range order {
+		if _, ok := m.Data[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == len(m.Data) {
+		return keys
+	}
+	known := len(keys)
+	for k := range m.Data {
+		if !contains(order, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[known:])
+	return keys
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CQCode 将数组消息转换为CQ码, 参数顺序是固定的
 func (m MessageSegment) CQCode() string {
-	cqcode := "[CQ:" + m.Type  // 消息类型
-	for k, v := range m.Data { // 消息参数
-		cqcode = cqcode + "," + k + "=" + EscapeCQCodeText(v)
+	var sb strings.Builder
+	sb.WriteString("[CQ:")
+	sb.WriteString(m.Type)       // 消息类型
+	for _, k := range m.keys() { // 消息参数
+		sb.WriteByte(',')
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(EscapeCQCodeText(m.Data[k]))
 	}
-	return cqcode + "]"
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// Equal 判断两个消息段是否相同, Data 为 nil 与为空视为相同
+func (m MessageSegment) Equal(other MessageSegment) bool {
+	if m.Type != other.Type || len(m.Data) != len(other.Data) {
+		return false
+	}
+	for k, v := range m.Data {
+		if v2, ok := other.Data[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
 }
 
 // Text 纯文本
@@ -149,6 +255,129 @@ func CustomMusic(subType, url, audio, title string) MessageSegment {
 	}
 }
 
+// AtAll @全体成员
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E6%9F%90%E4%BA%BA
+func AtAll() MessageSegment {
+	return At("all")
+}
+
+// AtWithName @某人, 账号不在群内时显示 name
+// https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#%E6%9F%90%E4%BA%BA
+func AtWithName(qq string, name string) MessageSegment {
+	return MessageSegment{
+		Type: "at",
+		Data: map[string]string{
+			"qq":   qq,
+			"name": name,
+		},
+	}
+}
+
+// FlashImage 闪照
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E5%9B%BE%E7%89%87
+func FlashImage(file string) MessageSegment {
+	return ImageWithOptions(file, &ImageOptions{Type: "flash"})
+}
+
+// ShowImage 秀图, id 为特效 ID, 40000-40005
+// https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#%E5%9B%BE%E7%89%87
+func ShowImage(file string, id int) MessageSegment {
+	return ImageWithOptions(file, &ImageOptions{Type: "show", ID: id})
+}
+
+// Video 短视频
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E7%9F%AD%E8%A7%86%E9%A2%91
+func Video(file string) MessageSegment {
+	return MessageSegment{
+		Type: "video",
+		Data: map[string]string{
+			"file": file,
+		},
+	}
+}
+
+// RPS 猜拳魔法表情
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E7%8C%9C%E6%8B%B3%E9%AD%94%E6%B3%95%E8%A1%A8%E6%83%85
+func RPS() MessageSegment {
+	return MessageSegment{
+		Type: "rps",
+		Data: map[string]string{},
+	}
+}
+
+// Dice 掷骰子魔法表情
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E6%8E%B7%E9%AA%B0%E5%AD%90%E9%AD%94%E6%B3%95%E8%A1%A8%E6%83%85
+func Dice() MessageSegment {
+	return MessageSegment{
+		Type: "dice",
+		Data: map[string]string{},
+	}
+}
+
+// Shake 窗口抖动（戳一戳）
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E7%AA%97%E5%8F%A3%E6%8A%96%E5%8A%A8%E6%88%B3%E4%B8%80%E6%88%B3-
+func Shake() MessageSegment {
+	return MessageSegment{
+		Type: "shake",
+		Data: map[string]string{},
+	}
+}
+
+// Anonymous 匿名发消息, ignore 为 true 时无法匿名也继续发送
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E5%8C%BF%E5%90%8D%E5%8F%91%E6%B6%88%E6%81%AF-
+func Anonymous(ignore bool) MessageSegment {
+	seg := MessageSegment{
+		Type: "anonymous",
+		Data: map[string]string{},
+	}
+	if ignore {
+		seg.Data["ignore"] = "1"
+	}
+	return seg
+}
+
+// Share 链接分享
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E9%93%BE%E6%8E%A5%E5%88%86%E4%BA%AB
+func Share(url, title, content, image string) MessageSegment {
+	seg := MessageSegment{
+		Type: "share",
+		Data: map[string]string{
+			"url":   url,
+			"title": title,
+		},
+	}
+	seg.set("content", content)
+	seg.set("image", image)
+	return seg
+}
+
+// Contact 推荐好友或群, type_ 为 qq 或 group
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E6%8E%A8%E8%8D%90%E5%A5%BD%E5%8F%8B
+func Contact(type_ string, id string) MessageSegment {
+	return MessageSegment{
+		Type: "contact",
+		Data: map[string]string{
+			"type": type_,
+			"id":   id,
+		},
+	}
+}
+
+// Location 位置
+// https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E4%BD%8D%E7%BD%AE
+func Location(lat, lon float64, title, content string) MessageSegment {
+	seg := MessageSegment{
+		Type: "location",
+		Data: map[string]string{
+			"lat": strconv.FormatFloat(lat, 'f', -1, 64),
+			"lon": strconv.FormatFloat(lon, 'f', -1, 64),
+		},
+	}
+	seg.set("title", title)
+	seg.set("content", content)
+	return seg
+}
+
 // Reply 回复
 // https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E5%9B%9E%E5%A4%8D
 func Reply(id string) MessageSegment {
@@ -182,7 +411,7 @@ func Node(id string) MessageSegment {
 	}
 }
 
-// CustomNode 自定义合并转发节点
+// CustomNode 自定义合并转发节点, content 会被转换为字符串, 需要以数组形式发送时请使用 NewForward
 // https://github.com/howmanybots/onebot/blob/master/v11/specs/message/segment.md#%E5%90%88%E5%B9%B6%E8%BD%AC%E5%8F%91%E8%87%AA%E5%AE%9A%E4%B9%89%E8%8A%82%E7%82%B9
 func CustomNode(nickname string, userId string, content interface{}) MessageSegment {
 	var str string
@@ -259,6 +488,37 @@ func TTS(text string) MessageSegment {
 	}
 }
 
+// RedBag 红包, 仅用于接收
+// https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#%E7%BA%A2%E5%8C%85
+func RedBag(title string) MessageSegment {
+	return MessageSegment{
+		Type: "redbag",
+		Data: map[string]string{
+			"title": title,
+		},
+	}
+}
+
+// CardImage 装逼大图
+// https://github.com/Mrs4s/go-cqhttp/blob/master/docs/cqhttp.md#cardimage
+func CardImage(file string, o *CardImageOptions) MessageSegment {
+	seg := MessageSegment{
+		Type: "cardimage",
+		Data: map[string]string{
+			"file": file,
+		},
+	}
+	if o != nil {
+		seg.setInt("minwidth", o.MinWidth)
+		seg.setInt("minheight", o.MinHeight)
+		seg.setInt("maxwidth", o.MaxWidth)
+		seg.setInt("maxheight", o.MaxHeight)
+		seg.set("source", o.Source)
+		seg.set("icon", o.Icon)
+	}
+	return seg
+}
+
 // ReplyWithMessage returns a reply message
 func ReplyWithMessage(messageID string, m ...MessageSegment) Message {
 	return append(Message{Reply(messageID)}, m...)
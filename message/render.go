@@ -0,0 +1,363 @@
+package message
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RenderOptions 渲染消息的可选参数
+type RenderOptions struct {
+	// Name 返回被@的账号的昵称, 为 nil 或返回空时显示账号
+	Name func(userID int64) string
+}
+
+// PlainText 将消息渲染为可读的纯文本, 非文本消息段渲染为 [图片] @昵称 [回复:id] 等
+func (m Message) PlainText(o *RenderOptions) string {
+	var sb strings.Builder
+	for _, seg := range m {
+		if seg.Type == "text" {
+			sb.WriteString(seg.Data["text"])
+			continue
+		}
+		sb.WriteString(seg.plainText(o))
+	}
+	return sb.String()
+}
+
+// Markdown 将消息渲染为 Markdown, 文本中的 Markdown 符号会被转义
+func (m Message) Markdown(o *RenderOptions) string {
+	var sb strings.Builder
+	for _, seg := range m {
+		switch seg.Type {
+		case "text":
+			sb.WriteString(escapeMarkdown(seg.Data["text"]))
+		case "image":
+			if u := seg.mediaURL(); u != "" {
+				sb.WriteString("![" + escapeMarkdown(seg.label()) + "](" + escapeMarkdownURL(u) + ")")
+				continue
+			}
+			sb.WriteString(escapeMarkdown(seg.plainText(o)))
+		default:
+			if u := seg.linkURL(); u != "" {
+				sb.WriteString("[" + escapeMarkdown(seg.plainText(o)) + "](" + escapeMarkdownURL(u) + ")")
+				continue
+			}
+			sb.WriteString(escapeMarkdown(seg.plainText(o)))
+		}
+	}
+	return sb.String()
+}
+
+// HTML 将消息渲染为 HTML, 文本均被转义, 只会输出 http 与 https 链接.
+// 非文本消息段渲染为带有 cq-<类型> class 的元素, 如 <span class="cq-at">@昵称</span>
+func (m Message) HTML(o *RenderOptions) string {
+	var sb strings.Builder
+	for _, seg := range m {
+		switch seg.Type {
+		case "text":
+			sb.WriteString(strings.Replace(html.EscapeString(seg.Data["text"]), "\n", "<br>", -1))
+		case "image":
+			if u := seg.mediaURL(); u != "" {
+				sb.WriteString(`<img class="cq-image" src="` + html.EscapeString(u) + `" alt="` + html.EscapeString(seg.label()) + `">`)
+				continue
+			}
+			sb.WriteString(`<span class="cq-image">` + html.EscapeString(seg.plainText(o)) + `</span>`)
+		default:
+			class := html.EscapeString("cq-" + seg.Type)
+			if u := seg.linkURL(); u != "" {
+				sb.WriteString(`<a class="` + class + `" href="` + html.EscapeString(u) + `" rel="noopener noreferrer" target="_blank">` +
+					html.EscapeString(seg.plainText(o)) + `</a>`)
+				continue
+			}
+			if text := seg.plainText(o); text != "" {
+				sb.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + `</span>`)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// segmentLabels 非文本消息段的显示名称
+var segmentLabels = map[string]string{
+	"face":      "表情",
+	"image":     "图片",
+	"record":    "语音",
+	"video":     "视频",
+	"rps":       "猜拳",
+	"dice":      "骰子",
+	"shake":     "窗口抖动",
+	"poke":      "戳一戳",
+	"share":     "分享",
+	"contact":   "推荐",
+	"location":  "位置",
+	"music":     "音乐",
+	"forward":   "合并转发",
+	"node":      "合并转发",
+	"xml":       "卡片消息",
+	"json":      "卡片消息",
+	"gift":      "礼物",
+	"tts":       "语音",
+	"redbag":    "红包",
+	"cardimage": "图片",
+}
+
+func (m MessageSegment) label() string {
+	if m.Type == "image" && m.Data["type"] == "flash" {
+		return "闪照"
+	}
+	if l, ok := segmentLabels[m.Type]; ok {
+		return l
+	}
+	return m.Type
+}
+
+// plainText 非文本消息段的纯文本形式
+func (m MessageSegment) plainText(o *RenderOptions) string {
+	switch m.Type {
+	case "anonymous":
+		return ""
+	case "at":
+		at, _ := m.AsAt()
+		if at.All() {
+			return "@全体成员"
+		}
+		if o != nil && o.Name != nil {
+			if id, err := strconv.ParseInt(at.QQ, 10, 64); err == nil {
+				if name := o.Name(id); name != "" {
+					return "@" + name
+				}
+			}
+		}
+		if at.Name != "" {
+			return "@" + at.Name
+		}
+		return "@" + at.QQ
+	case "reply":
+		return "[回复:" + m.Data["id"] + "]"
+	case "contact":
+		if m.Data["type"] == "group" {
+			return "[推荐群:" + m.Data["id"] + "]"
+		}
+		return "[推荐好友:" + m.Data["id"] + "]"
+	case "share", "location", "music", "redbag":
+		if title := m.Data["title"]; title != "" {
+			return "[" + m.label() + ":" + title + "]"
+		}
+	}
+	return "[" + m.label() + "]"
+}
+
+// mediaURL 返回图片的 http(s) 链接
+func (m MessageSegment) mediaURL() string {
+	for _, u := range []string{m.Data["url"], m.Data["file"]} {
+		if safeURL(u) {
+			return u
+		}
+	}
+	return ""
+}
+
+// linkURL 返回可以作为链接显示的消息段的 http(s) 链接
+func (m MessageSegment) linkURL() string {
+	switch m.Type {
+	case "record", "video":
+		return m.mediaURL()
+	case "share", "music":
+		if safeURL(m.Data["url"]) {
+			return m.Data["url"]
+		}
+	}
+	return ""
+}
+
+func safeURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+const markdownSpecial = "\\`*_~[]<>#|"
+
+func escapeMarkdown(s string) string {
+	if !strings.ContainsAny(s, markdownSpecial) {
+		return s
+	}
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(markdownSpecial, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func escapeMarkdownURL(s string) string {
+	return strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(s)
+}
+
+// ParseMarkdown 将 Markdown 的子集转换为消息
+//
+// 支持反斜杠转义, 图片 ![alt](url), 链接 [text](url) 与 <url>, 强调, 删除线,
+// 行内代码, 代码块与标题. 图片转换为图片消息段, 链接转换为 "text (url)",
+// 其余格式只保留文本内容
+func ParseMarkdown(text string) Message {
+	var (
+		b     = New()
+		lines = strings.SplitAfter(text, "\n")
+	)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") { // 代码块原样保留
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				b.Text(lines[i])
+			}
+			continue
+		}
+		if h := strings.TrimLeft(trimmed, "#"); len(h) < len(trimmed) && len(trimmed)-len(h) <= 6 && strings.HasPrefix(h, " ") {
+			line = strings.TrimSpace(h)
+			if strings.HasSuffix(lines[i], "\n") {
+				line += "\n"
+			}
+		}
+		parseMarkdownInline(b, line)
+	}
+	return b.Build()
+}
+
+func parseMarkdownInline(b *Builder, s string) {
+	var text strings.Builder
+	flush := func() {
+		b.Text(text.String())
+		text.Reset()
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(markdownPunct, s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			fence := s[i : i+n]
+			if j := strings.Index(s[i+n:], fence); j >= 0 {
+				text.WriteString(strings.TrimSpace(s[i+n : i+n+j]))
+				i += n + j + n
+				continue
+			}
+			text.WriteString(fence)
+			i += n
+			continue
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if _, u, n, ok := markdownLink(s[i+1:]); ok {
+				flush()
+				b.Image(u)
+				i += 1 + n
+				continue
+			}
+		case c == '[':
+			if label, u, n, ok := markdownLink(s[i:]); ok {
+				flush()
+				parseMarkdownInline(b, label)
+				if label != u {
+					b.Text(" (" + u + ")")
+				}
+				i += n
+				continue
+			}
+		case c == '<':
+			if j := strings.IndexByte(s[i:], '>'); j > 0 && safeURL(s[i+1:i+j]) {
+				text.WriteString(s[i+1 : i+j])
+				i += j + 1
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			if inner, n, ok := markdownEmphasis(s, i); ok {
+				flush()
+				parseMarkdownInline(b, inner)
+				i += n
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+}
+
+const markdownPunct = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// markdownLink 解析 s 开头的 [label](url), 返回 label, url 与消耗的字节数
+func markdownLink(s string) (label, u string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if !strings.HasPrefix(s[i+1:], "(") {
+				return "", "", 0, false
+			}
+			j := strings.IndexByte(s[i+2:], ')')
+			if j < 0 {
+				return "", "", 0, false
+			}
+			u = strings.TrimSpace(s[i+2 : i+2+j])
+			if !safeURL(u) {
+				return "", "", 0, false
+			}
+			return s[1:i], u, i + 2 + j + 1, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// markdownEmphasis 解析 s[i:] 开头的 **x** *x* __x__ _x_ ~~x~~, 下划线不会匹配单词内部
+func markdownEmphasis(s string, i int) (inner string, n int, ok bool) {
+	delim := s[i : i+1]
+	if strings.HasPrefix(s[i:], delim+delim) {
+		delim += delim
+	} else if delim == "~" {
+		return "", 0, false
+	}
+	start := i + len(delim)
+	if delim[0] == '_' && i > 0 && isWordRune(s[:i], true) {
+		return "", 0, false
+	}
+	if start >= len(s) || s[start] == ' ' {
+		return "", 0, false
+	}
+	for j := start + 1; j <= len(s)-len(delim); j++ {
+		if s[j-1] == '\\' || !strings.HasPrefix(s[j:], delim) || s[j-1] == ' ' {
+			continue
+		}
+		end := j + len(delim)
+		if delim[0] == '_' && isWordRune(s[end:], false) {
+			continue
+		}
+		return s[start:j], end - i, true
+	}
+	return "", 0, false
+}
+
+// isWordRune 判断 s 的最后一个 (last 为 true) 或第一个字符是否为字母或数字
+func isWordRune(s string, last bool) bool {
+	var r rune
+	if last {
+		r, _ = utf8.DecodeLastRuneInString(s)
+	} else {
+		r, _ = utf8.DecodeRuneInString(s)
+	}
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,48 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	m := Message{
+		Reply("42"),
+		At("123"),
+		Text(" <b>1*2</b>\n"),
+		Image("https://example.com/a.png"),
+		Image("abc.image"),
+		Face("14"),
+		AtAll(),
+		Share("javascript:alert(1)", "t", "", ""),
+	}
+	o := &RenderOptions{Name: func(userID int64) string {
+		if userID == 123 {
+			return "Alice"
+		}
+		return ""
+	}}
+	assert.Equal(t, "[回复:42]@Alice <b>1*2</b>\n[图片][图片][表情]@全体成员[分享:t]", m.PlainText(o))
+	assert.Equal(t, "@123", Message{At("123")}.PlainText(nil))
+	assert.Equal(t, `\[回复:42\]@Alice \<b\>1\*2\</b\>`+"\n"+`![图片](https://example.com/a.png)\[图片\]\[表情\]@全体成员\[分享:t\]`, m.Markdown(o))
+	assert.Equal(t, `<span class="cq-reply">[回复:42]</span><span class="cq-at">@Alice</span> &lt;b&gt;1*2&lt;/b&gt;<br>`+
+		`<img class="cq-image" src="https://example.com/a.png" alt="图片"><span class="cq-image">[图片]</span>`+
+		`<span class="cq-face">[表情]</span><span class="cq-at">@全体成员</span><span class="cq-share">[分享:t]</span>`, m.HTML(o))
+	assert.Equal(t, `<a class="cq-share" href="https://example.com/?a=1&amp;b=2" rel="noopener noreferrer" target="_blank">[分享:&lt;t&gt;]</a>`,
+		Message{Share("https://example.com/?a=1&b=2", "<t>", "", "")}.HTML(nil))
+}
+
+func TestParseMarkdown(t *testing.T) {
+	assert.Equal(t, Message{
+		Text("标题\nhello bold italic del code snake_case_name * x\n"),
+		Image("https://example.com/a.png"),
+		Text(" see docs (https://example.com/docs) https://example.com [x]\nfmt.Println(1)\n"),
+	}, ParseMarkdown("## 标题\nhello **bold** _italic_ ~~del~~ `code` snake_case_name * x\n"+
+		"![图片](https://example.com/a.png) see [docs](https://example.com/docs) <https://example.com> \\[x\\]\n"+
+		"```go\nfmt.Println(1)\n```"))
+	assert.Equal(t, Message{Text("[js](javascript:alert(1))")}, ParseMarkdown("[js](javascript:alert(1))"))
+
+	m := Message{Text("a*b_c [x] #1\n"), Image("https://example.com/a.png")}
+	assert.Equal(t, m, ParseMarkdown(m.Markdown(nil)))
+}
@@ -0,0 +1,439 @@
+package message
+
+import (
+	"strconv"
+)
+
+// ImageSegment 图片消息段
+type ImageSegment struct {
+	File    string // 图片文件名
+	Type    string // 图片类型, flash 为闪照, show 为秀图, 为空时为普通图片
+	SubType string // 图片子类型, go-cqhttp 扩展
+	URL     string // 图片 URL, 仅接收时有效
+	Cache   bool   // 是否使用已缓存的文件, 默认为 true
+	Proxy   bool   // 是否通过代理下载文件, 默认为 true
+	Timeout int    // 下载网络文件的超时时间(秒), 为 0 时不超时
+	ID      int    // 秀图特效 ID, go-cqhttp 扩展
+	Threads int    // 下载网络文件的线程数, go-cqhttp 扩展
+}
+
+// ImageOptions 图片的可选参数
+type ImageOptions struct {
+	Type    string // flash 为闪照, show 为秀图
+	SubType string // 图片子类型, go-cqhttp 扩展
+	NoCache bool   // 不使用已缓存的文件
+	NoProxy bool   // 不通过代理下载文件
+	Timeout int    // 下载网络文件的超时时间(秒)
+	ID      int    // 秀图特效 ID, go-cqhttp 扩展
+	Threads int    // 下载网络文件的线程数, go-cqhttp 扩展
+}
+
+// RecordSegment 语音消息段
+type RecordSegment struct {
+	File    string // 语音文件名
+	Magic   bool   // 是否为变声
+	URL     string // 语音 URL, 仅接收时有效
+	Cache   bool   // 是否使用已缓存的文件, 默认为 true
+	Proxy   bool   // 是否通过代理下载文件, 默认为 true
+	Timeout int    // 下载网络文件的超时时间(秒), 为 0 时不超时
+}
+
+// RecordOptions 语音的可选参数
+type RecordOptions struct {
+	Magic   bool // 变声
+	NoCache bool // 不使用已缓存的文件
+	NoProxy bool // 不通过代理下载文件
+	Timeout int  // 下载网络文件的超时时间(秒)
+}
+
+// AtSegment @消息段
+type AtSegment struct {
+	QQ   string // 被@的账号, all 表示全体成员
+	Name string // 账号不在群内时显示的名称, go-cqhttp 扩展
+}
+
+// ReplySegment 回复消息段
+type ReplySegment struct {
+	ID   string // 回复的消息 ID
+	Text string // 自定义回复的内容, go-cqhttp 扩展
+	QQ   int64  // 自定义回复的发送者, go-cqhttp 扩展
+	Time int64  // 自定义回复的时间戳, go-cqhttp 扩展
+	Seq  int64  // 自定义回复的消息序号, go-cqhttp 扩展
+}
+
+// ReplyOptions 自定义回复的参数, go-cqhttp 扩展
+type ReplyOptions struct {
+	Text string // 回复的内容
+	QQ   int64  // 发送者
+	Time int64  // 时间戳
+	Seq  int64  // 消息序号
+}
+
+// FaceSegment QQ表情消息段
+type FaceSegment struct {
+	ID int // 表情 ID
+}
+
+// VideoSegment 短视频消息段
+type VideoSegment struct {
+	File    string // 视频文件名
+	Cover   string // 视频封面, go-cqhttp 扩展
+	URL     string // 视频 URL, 仅接收时有效
+	Cache   bool   // 是否使用已缓存的文件, 默认为 true
+	Proxy   bool   // 是否通过代理下载文件, 默认为 true
+	Timeout int    // 下载网络文件的超时时间(秒), 为 0 时不超时
+	Threads int    // 下载网络文件的线程数, go-cqhttp 扩展
+}
+
+// VideoOptions 短视频的可选参数
+type VideoOptions struct {
+	Cover   string // 视频封面, go-cqhttp 扩展
+	NoCache bool   // 不使用已缓存的文件
+	NoProxy bool   // 不通过代理下载文件
+	Timeout int    // 下载网络文件的超时时间(秒)
+	Threads int    // 下载网络文件的线程数, go-cqhttp 扩展
+}
+
+// ShareSegment 链接分享消息段
+type ShareSegment struct {
+	URL     string // 链接
+	Title   string // 标题
+	Content string // 内容描述
+	Image   string // 图片 URL
+}
+
+// ContactSegment 推荐好友或群消息段
+type ContactSegment struct {
+	Type string // qq 为好友, group 为群
+	ID   int64  // 账号或群号
+}
+
+// LocationSegment 位置消息段
+type LocationSegment struct {
+	Lat     float64 // 纬度
+	Lon     float64 // 经度
+	Title   string  // 标题
+	Content string  // 内容描述
+}
+
+// MusicSegment 音乐分享消息段
+type MusicSegment struct {
+	Type    string // qq, 163, xm 或 custom
+	ID      string // 歌曲 ID, 自定义分享时为空
+	SubType string // 自定义分享的音乐类型
+	URL     string // 自定义分享的跳转链接
+	Audio   string // 自定义分享的音频链接
+	Title   string // 自定义分享的标题
+	Content string // 自定义分享的内容描述
+	Image   string // 自定义分享的图片
+}
+
+// CustomMusicOptions 自定义音乐分享的可选参数
+type CustomMusicOptions struct {
+	SubType string // 音乐类型, go-cqhttp 扩展
+	Content string // 内容描述
+	Image   string // 图片 URL
+}
+
+// AnonymousSegment 匿名发消息消息段
+type AnonymousSegment struct {
+	Ignore bool // 无法匿名时是否继续发送
+}
+
+// RedBagSegment 红包消息段, go-cqhttp 扩展
+type RedBagSegment struct {
+	Title string // 祝福语
+}
+
+// CardImageSegment 装逼大图消息段, go-cqhttp 扩展
+type CardImageSegment struct {
+	File      string // 图片文件
+	MinWidth  int64  // 最小宽度, 默认 400
+	MinHeight int64  // 最小高度, 默认 400
+	MaxWidth  int64  // 最大宽度, 默认 500
+	MaxHeight int64  // 最大高度, 默认 1000
+	Source    string // 来源名称
+	Icon      string // 来源图标 URL
+}
+
+// CardImageOptions 装逼大图的可选参数
+type CardImageOptions struct {
+	MinWidth  int64  // 最小宽度
+	MinHeight int64  // 最小高度
+	MaxWidth  int64  // 最大宽度
+	MaxHeight int64  // 最大高度
+	Source    string // 来源名称
+	Icon      string // 来源图标 URL
+}
+
+// ImageWithOptions 带可选参数的图片
+func ImageWithOptions(file string, o *ImageOptions) MessageSegment {
+	seg := Image(file)
+	if o == nil {
+		return seg
+	}
+	seg.set("type", o.Type)
+	seg.set("subType", o.SubType)
+	seg.setFlag("cache", o.NoCache, "0")
+	seg.setFlag("proxy", o.NoProxy, "0")
+	seg.setInt("timeout", int64(o.Timeout))
+	seg.setInt("id", int64(o.ID))
+	seg.setInt("c", int64(o.Threads))
+	return seg
+}
+
+// RecordWithOptions 带可选参数的语音
+func RecordWithOptions(file string, o *RecordOptions) MessageSegment {
+	seg := Record(file)
+	if o == nil {
+		return seg
+	}
+	seg.setFlag("magic", o.Magic, "1")
+	seg.setFlag("cache", o.NoCache, "0")
+	seg.setFlag("proxy", o.NoProxy, "0")
+	seg.setInt("timeout", int64(o.Timeout))
+	return seg
+}
+
+// VideoWithOptions 带可选参数的短视频
+func VideoWithOptions(file string, o *VideoOptions) MessageSegment {
+	seg := Video(file)
+	if o == nil {
+		return seg
+	}
+	seg.set("cover", o.Cover)
+	seg.setFlag("cache", o.NoCache, "0")
+	seg.setFlag("proxy", o.NoProxy, "0")
+	seg.setInt("timeout", int64(o.Timeout))
+	seg.setInt("c", int64(o.Threads))
+	return seg
+}
+
+// CustomMusicWithOptions 带可选参数的音乐自定义分享
+func CustomMusicWithOptions(url, audio, title string, o *CustomMusicOptions) MessageSegment {
+	seg := MessageSegment{
+		Type: "music",
+		Data: map[string]string{
+			"type":  "custom",
+			"url":   url,
+			"audio": audio,
+			"title": title,
+		},
+	}
+	if o != nil {
+		seg.set("sub_type", o.SubType)
+		seg.set("content", o.Content)
+		seg.set("image", o.Image)
+	}
+	return seg
+}
+
+// ReplyWithOptions 自定义回复, go-cqhttp 扩展
+func ReplyWithOptions(id string, o *ReplyOptions) MessageSegment {
+	seg := Reply(id)
+	if o == nil {
+		return seg
+	}
+	seg.set("text", o.Text)
+	seg.setInt("qq", o.QQ)
+	seg.setInt("time", o.Time)
+	seg.setInt("seq", o.Seq)
+	return seg
+}
+
+// AsImage 将消息段解析为图片
+func (m MessageSegment) AsImage() (ImageSegment, bool) {
+	if m.Type != "image" {
+		return ImageSegment{}, false
+	}
+	return ImageSegment{
+		File:    m.Data["file"],
+		Type:    m.Data["type"],
+		SubType: m.Data["subType"],
+		URL:     m.Data["url"],
+		Cache:   m.Data["cache"] != "0",
+		Proxy:   m.Data["proxy"] != "0",
+		Timeout: m.int("timeout"),
+		ID:      m.int("id"),
+		Threads: m.int("c"),
+	}, true
+}
+
+// AsRecord 将消息段解析为语音
+func (m MessageSegment) AsRecord() (RecordSegment, bool) {
+	if m.Type != "record" {
+		return RecordSegment{}, false
+	}
+	return RecordSegment{
+		File:    m.Data["file"],
+		Magic:   m.Data["magic"] == "1" || m.Data["magic"] == "true",
+		URL:     m.Data["url"],
+		Cache:   m.Data["cache"] != "0",
+		Proxy:   m.Data["proxy"] != "0",
+		Timeout: m.int("timeout"),
+	}, true
+}
+
+// AsAt 将消息段解析为@
+func (m MessageSegment) AsAt() (AtSegment, bool) {
+	if m.Type != "at" {
+		return AtSegment{}, false
+	}
+	return AtSegment{QQ: m.Data["qq"], Name: m.Data["name"]}, true
+}
+
+// All 是否为@全体成员
+func (s AtSegment) All() bool { return s.QQ == "all" }
+
+// AsVideo 将消息段解析为短视频
+func (m MessageSegment) AsVideo() (VideoSegment, bool) {
+	if m.Type != "video" {
+		return VideoSegment{}, false
+	}
+	return VideoSegment{
+		File:    m.Data["file"],
+		Cover:   m.Data["cover"],
+		URL:     m.Data["url"],
+		Cache:   m.Data["cache"] != "0",
+		Proxy:   m.Data["proxy"] != "0",
+		Timeout: m.int("timeout"),
+		Threads: m.int("c"),
+	}, true
+}
+
+// AsShare 将消息段解析为链接分享
+func (m MessageSegment) AsShare() (ShareSegment, bool) {
+	if m.Type != "share" {
+		return ShareSegment{}, false
+	}
+	return ShareSegment{
+		URL:     m.Data["url"],
+		Title:   m.Data["title"],
+		Content: m.Data["content"],
+		Image:   m.Data["image"],
+	}, true
+}
+
+// AsContact 将消息段解析为推荐好友或群
+func (m MessageSegment) AsContact() (ContactSegment, bool) {
+	if m.Type != "contact" {
+		return ContactSegment{}, false
+	}
+	return ContactSegment{Type: m.Data["type"], ID: m.int64("id")}, true
+}
+
+// AsLocation 将消息段解析为位置
+func (m MessageSegment) AsLocation() (LocationSegment, bool) {
+	if m.Type != "location" {
+		return LocationSegment{}, false
+	}
+	lat, _ := strconv.ParseFloat(m.Data["lat"], 64)
+	lon, _ := strconv.ParseFloat(m.Data["lon"], 64)
+	return LocationSegment{
+		Lat:     lat,
+		Lon:     lon,
+		Title:   m.Data["title"],
+		Content: m.Data["content"],
+	}, true
+}
+
+// AsMusic 将消息段解析为音乐分享
+func (m MessageSegment) AsMusic() (MusicSegment, bool) {
+	if m.Type != "music" {
+		return MusicSegment{}, false
+	}
+	return MusicSegment{
+		Type:    m.Data["type"],
+		ID:      m.Data["id"],
+		SubType: m.Data["sub_type"],
+		URL:     m.Data["url"],
+		Audio:   m.Data["audio"],
+		Title:   m.Data["title"],
+		Content: m.Data["content"],
+		Image:   m.Data["image"],
+	}, true
+}
+
+// AsAnonymous 将消息段解析为匿名发消息
+func (m MessageSegment) AsAnonymous() (AnonymousSegment, bool) {
+	if m.Type != "anonymous" {
+		return AnonymousSegment{}, false
+	}
+	return AnonymousSegment{Ignore: m.Data["ignore"] == "1" || m.Data["ignore"] == "true"}, true
+}
+
+// AsRedBag 将消息段解析为红包
+func (m MessageSegment) AsRedBag() (RedBagSegment, bool) {
+	if m.Type != "redbag" {
+		return RedBagSegment{}, false
+	}
+	return RedBagSegment{Title: m.Data["title"]}, true
+}
+
+// AsCardImage 将消息段解析为装逼大图
+func (m MessageSegment) AsCardImage() (CardImageSegment, bool) {
+	if m.Type != "cardimage" {
+		return CardImageSegment{}, false
+	}
+	return CardImageSegment{
+		File:      m.Data["file"],
+		MinWidth:  m.int64("minwidth"),
+		MinHeight: m.int64("minheight"),
+		MaxWidth:  m.int64("maxwidth"),
+		MaxHeight: m.int64("maxheight"),
+		Source:    m.Data["source"],
+		Icon:      m.Data["icon"],
+	}, true
+}
+
+// AsReply 将消息段解析为回复
+func (m MessageSegment) AsReply() (ReplySegment, bool) {
+	if m.Type != "reply" {
+		return ReplySegment{}, false
+	}
+	return ReplySegment{
+		ID:   m.Data["id"],
+		Text: m.Data["text"],
+		QQ:   m.int64("qq"),
+		Time: m.int64("time"),
+		Seq:  m.int64("seq"),
+	}, true
+}
+
+// AsFace 将消息段解析为QQ表情
+func (m MessageSegment) AsFace() (FaceSegment, bool) {
+	if m.Type != "face" {
+		return FaceSegment{}, false
+	}
+	return FaceSegment{ID: m.int("id")}, true
+}
+
+// set 设置非空参数
+func (m *MessageSegment) set(key, value string) {
+	if value != "" {
+		m.Data[key] = value
+	}
+}
+
+// setFlag flag 为 true 时设置参数
+func (m *MessageSegment) setFlag(key string, flag bool, value string) {
+	if flag {
+		m.Data[key] = value
+	}
+}
+
+// setInt 设置非零参数
+func (m *MessageSegment) setInt(key string, value int64) {
+	if value != 0 {
+		m.Data[key] = strconv.FormatInt(value, 10)
+	}
+}
+
+func (m MessageSegment) int(key string) int {
+	return int(m.int64(key))
+}
+
+func (m MessageSegment) int64(key string) int64 {
+	i, _ := strconv.ParseInt(m.Data[key], 10, 64)
+	return i
+}
@@ -0,0 +1,53 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentViews(t *testing.T) {
+	seg := ImageWithOptions("a.jpg", &ImageOptions{Type: "show", ID: 40001, NoCache: true, Timeout: 5})
+	assert.Equal(t, "[CQ:image,file=a.jpg,type=show,cache=0,timeout=5,id=40001]", seg.CQCode())
+	img, ok := seg.AsImage()
+	assert.True(t, ok)
+	assert.Equal(t, ImageSegment{File: "a.jpg", Type: "show", Proxy: true, Timeout: 5, ID: 40001}, img)
+	_, ok = seg.AsAt()
+	assert.False(t, ok)
+
+	rec, ok := RecordWithOptions("b.amr", &RecordOptions{Magic: true}).AsRecord()
+	assert.True(t, ok)
+	assert.Equal(t, RecordSegment{File: "b.amr", Magic: true, Cache: true, Proxy: true}, rec)
+
+	reply, _ := ReplyWithOptions("1", &ReplyOptions{Text: "hi", QQ: 10001}).AsReply()
+	assert.Equal(t, ReplySegment{ID: "1", Text: "hi", QQ: 10001}, reply)
+	at, _ := At("10001").AsAt()
+	assert.Equal(t, "10001", at.QQ)
+	face, _ := Face("178").AsFace()
+	assert.Equal(t, 178, face.ID)
+}
+
+func TestExtendedSegments(t *testing.T) {
+	at, _ := AtAll().AsAt()
+	assert.True(t, at.All())
+	assert.Equal(t, "[CQ:at,qq=10001,name=张三]", AtWithName("10001", "张三").CQCode())
+	assert.Equal(t, "[CQ:image,file=a.jpg,type=show,id=40002]", ShowImage("a.jpg", 40002).CQCode())
+	assert.Equal(t, "[CQ:dice]", Dice().CQCode())
+	assert.Equal(t, "[CQ:anonymous,ignore=1]", Anonymous(true).CQCode())
+
+	loc, ok := Location(39.9, 116.39, "北京", "").AsLocation()
+	assert.True(t, ok)
+	assert.Equal(t, LocationSegment{Lat: 39.9, Lon: 116.39, Title: "北京"}, loc)
+	share, _ := Share("http://a", "t", "c", "").AsShare()
+	assert.Equal(t, ShareSegment{URL: "http://a", Title: "t", Content: "c"}, share)
+	contact, _ := Contact("group", "123").AsContact()
+	assert.Equal(t, ContactSegment{Type: "group", ID: 123}, contact)
+	music, _ := CustomMusicWithOptions("http://a", "http://b", "t", &CustomMusicOptions{Content: "c", Image: "i"}).AsMusic()
+	assert.Equal(t, MusicSegment{Type: "custom", URL: "http://a", Audio: "http://b", Title: "t", Content: "c", Image: "i"}, music)
+	video, _ := VideoWithOptions("v.mp4", &VideoOptions{Cover: "c.jpg"}).AsVideo()
+	assert.Equal(t, VideoSegment{File: "v.mp4", Cover: "c.jpg", Cache: true, Proxy: true}, video)
+	card, _ := CardImage("a.jpg", &CardImageOptions{MaxWidth: 600, Source: "s"}).AsCardImage()
+	assert.Equal(t, CardImageSegment{File: "a.jpg", MaxWidth: 600, Source: "s"}, card)
+	bag, _ := RedBag("恭喜发财").AsRedBag()
+	assert.Equal(t, "恭喜发财", bag.Title)
+}
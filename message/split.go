@@ -0,0 +1,122 @@
+package message
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxLength 默认每条消息的最大字符数
+	DefaultMaxLength = 3000
+	// DefaultMaxSegments 默认每条消息的最大消息段数
+	DefaultMaxSegments = 50
+)
+
+// SplitOptions 拆分消息的限制
+type SplitOptions struct {
+	MaxLength   int // 每条消息的最大字符数, 默认为 DefaultMaxLength
+	MaxSegments int // 每条消息的最大消息段数, 默认为 DefaultMaxSegments
+}
+
+func (o *SplitOptions) limits() (maxLength, maxSegments int) {
+	if o != nil {
+		maxLength, maxSegments = o.MaxLength, o.MaxSegments
+	}
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if maxSegments <= 0 {
+		maxSegments = DefaultMaxSegments
+	}
+	return
+}
+
+// Exceeds 判断消息是否超出限制
+func (m Message) Exceeds(o *SplitOptions) bool {
+	maxLength, maxSegments := o.limits()
+	return len(m) > maxSegments || m.Length() > maxLength
+}
+
+// Length 返回消息的字符数, 非文本消息段按其 CQ 码计算
+func (m Message) Length() int {
+	n := 0
+	for _, seg := range m {
+		n += seg.length()
+	}
+	return n
+}
+
+func (m MessageSegment) length() int {
+	if m.Type == "text" {
+		return utf8.RuneCountInString(m.Data["text"])
+	}
+	return utf8.RuneCountInString(m.CQCode())
+}
+
+// Split 将消息拆分为多条不超出限制的消息
+//
+// 只会在文本中拆分, 且不会拆开 UTF-8 字符, 会尽量在换行处拆分;
+// 单个超出限制的非文本消息段会单独作为一条消息
+func Split(m Message, o *SplitOptions) []Message {
+	maxLength, maxSegments := o.limits()
+	var (
+		parts  []Message
+		cur    Message
+		length int
+	)
+	flush := func() {
+		if len(cur) > 0 {
+			parts = append(parts, cur)
+		}
+		cur, length = nil, 0
+	}
+	for _, seg := range m {
+		if seg.Type != "text" {
+			n := seg.length()
+			if len(cur) > 0 && (length+n > maxLength || len(cur)+1 > maxSegments) {
+				flush()
+			}
+			cur = append(cur, seg)
+			length += n
+			continue
+		}
+		text := seg.Data["text"]
+		for text != "" {
+			if len(cur) > 0 && (length >= maxLength || len(cur)+1 > maxSegments) {
+				flush()
+			}
+			chunk, rest := cutText(text, maxLength-length, len(cur) == 0)
+			if chunk == "" {
+				flush()
+				continue
+			}
+			cur = append(cur, Text(chunk))
+			length += utf8.RuneCountInString(chunk)
+			text = rest
+			if rest != "" {
+				flush()
+			}
+		}
+	}
+	flush()
+	return parts
+}
+
+// cutText 截取 text 中不超过 n 个字符的前缀, 优先在换行处截断.
+// fresh 为 false 时表示当前消息已有内容, 此时找不到换行则返回空, 在新消息中继续
+func cutText(text string, n int, fresh bool) (string, string) {
+	if utf8.RuneCountInString(text) <= n {
+		return text, ""
+	}
+	end := 0
+	for i := 0; i < n; i++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	if nl := strings.LastIndexByte(text[:end], '\n'); nl >= 0 && (!fresh || nl >= end/2) {
+		end = nl + 1
+	} else if !fresh {
+		return "", text
+	}
+	return text[:end], text[end:]
+}
@@ -0,0 +1,29 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	o := &SplitOptions{MaxLength: 10, MaxSegments: 3}
+	assert.Equal(t, []Message{{Text("short")}}, Split(Message{Text("short")}, o))
+
+	parts := Split(Message{Text("一二三四五六七八九十壹贰")}, o)
+	assert.Equal(t, []Message{{Text("一二三四五六七八九十")}, {Text("壹贰")}}, parts)
+
+	parts = Split(Message{Text("line1\nline2\nline3")}, o)
+	assert.Equal(t, []Message{{Text("line1\n")}, {Text("line2\n")}, {Text("line3")}}, parts)
+
+	parts = Split(Message{Face("1"), Text("ab"), Face("2"), Face("3"), Text("c")}, &SplitOptions{MaxLength: 100, MaxSegments: 3})
+	assert.Equal(t, []Message{{Face("1"), Text("ab"), Face("2")}, {Face("3"), Text("c")}}, parts)
+
+	parts = Split(Message{Text("a"), Image("very-long-image-file.jpg"), Text("b")}, o)
+	assert.Equal(t, []Message{{Text("a")}, {Image("very-long-image-file.jpg")}, {Text("b")}}, parts)
+
+	m := Message{Text("abc\ndefghijklmnopqrstuvwxyz")}
+	for _, part := range Split(m, o) {
+		assert.False(t, part.Exceeds(o))
+	}
+}
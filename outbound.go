@@ -0,0 +1,148 @@
+package zero
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OutboundConfig 是发送消息的频率限制, Interval 和 TargetInterval 都为 0 时不限制
+//
+// 超过频率的消息会排队等待发送而不会被丢弃, 使用 Send 回复的消息优先于直接发送的消息
+type OutboundConfig struct {
+	Interval       time.Duration `json:"interval"`        // 任意两条消息的最小发送间隔
+	TargetInterval time.Duration `json:"target_interval"` // 同一群或用户两条消息的最小发送间隔
+	Jitter         time.Duration `json:"jitter"`          // 在发送间隔上增加的最大随机时间
+}
+
+func (c *OutboundConfig) enabled() bool {
+	return c.Interval > 0 || c.TargetInterval > 0
+}
+
+func (c *OutboundConfig) jitter() time.Duration {
+	if c.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(c.Jitter)))
+}
+
+// outboundJob 是等待发送的消息
+type outboundJob struct {
+	target string
+	send   func()
+	done   chan struct{}
+}
+
+// outboundQueue 按频率限制依次发送消息
+type outboundQueue struct {
+	mu sync.Mutex
+	// jobs[0] 为回复, jobs[1] 为其他消息
+	jobs   [2][]*outboundJob
+	notify chan struct{}
+	once   sync.Once
+	// 下一条消息最早的发送时间
+	next       time.Time
+	targetNext map[string]time.Time
+}
+
+var outbound = &outboundQueue{
+	notify:     make(chan struct{}, 1),
+	targetNext: map[string]time.Time{},
+}
+
+// do 按频率限制调用 send, 并等待其完成
+func (q *outboundQueue) do(target string, reply bool, send func()) {
+	if !BotConfig.Outbound.enabled() {
+		send()
+		return
+	}
+	q.once.Do(func() { go q.loop() })
+	job := &outboundJob{target: target, send: send, done: make(chan struct{})}
+	i := 1
+	if reply {
+		i = 0
+	}
+	q.mu.Lock()
+	q.jobs[i] = append(q.jobs[i], job)
+	q.mu.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	<-job.done
+}
+
+func (q *outboundQueue) loop() {
+	for {
+		q.mu.Lock()
+		job, wait := q.pick(time.Now())
+		q.mu.Unlock()
+		if job == nil {
+			if wait <= 0 {
+				<-q.notify
+				continue
+			}
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-q.notify:
+				t.Stop()
+			}
+			continue
+		}
+		// 只有频率限制需要排队, 发送在新的 goroutine 中进行, 避免一次调用超时阻塞之后的所有消息
+		q.sent(job.target, time.Now())
+		go func() {
+			job.send()
+			close(job.done)
+		}()
+	}
+}
+
+// pick 取出可以发送的消息, 没有时返回需要等待的时间, 队列为空时等待时间为 0
+func (q *outboundQueue) pick(now time.Time) (*outboundJob, time.Duration) {
+	if now.Before(q.next) {
+		return nil, q.next.Sub(now)
+	}
+	var wait time.Duration
+	for i := range q.jobs {
+		for j, job := range q.jobs[i] {
+			next, ok := q.targetNext[job.target]
+			if !ok || !now.Before(next) {
+				q.jobs[i] = append(q.jobs[i][:j], q.jobs[i][j+1:]...)
+				return job, 0
+			}
+			if d := next.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+		}
+	}
+	return nil, wait
+}
+
+// sent 记录消息发送的时间
+func (q *outboundQueue) sent(target string, now time.Time) {
+	conf := &BotConfig.Outbound
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.next = now.Add(conf.Interval + conf.jitter())
+	if len(q.targetNext) > 1024 {
+		for k, next := range q.targetNext {
+			if now.After(next) {
+				delete(q.targetNext, k)
+			}
+		}
+	}
+	if conf.TargetInterval > 0 {
+		q.targetNext[target] = now.Add(conf.TargetInterval + conf.jitter())
+	}
+}
+
+func groupTarget(groupID int64) string {
+	return "group:" + strconv.FormatInt(groupID, 10)
+}
+
+func privateTarget(userID int64) string {
+	return "private:" + strconv.FormatInt(userID, 10)
+}
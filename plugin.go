@@ -1,5 +1,11 @@
 package zero
 
+import (
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
 // PluginInfo is the plugin's information
 type PluginInfo struct {
 	Author     string // 作者
@@ -8,7 +14,12 @@ type PluginInfo struct {
 	Details    string // 插件说明
 }
 
-var pluginPool []IPlugin
+var (
+	pluginPool []IPlugin
+	// 插件所在目录 -> 插件
+	pluginDirs = map[string]IPlugin{}
+	pluginLock = sync.RWMutex{}
+)
 
 // IPlugin is the plugin of the ZeroBot
 type IPlugin interface {
@@ -20,5 +31,32 @@ type IPlugin interface {
 
 // RegisterPlugin register the plugin to ZeroBot
 func RegisterPlugin(plugin IPlugin) {
+	_, file, _, _ := runtime.Caller(1) // who calls this method
+	pluginLock.Lock()
+	defer pluginLock.Unlock()
 	pluginPool = append(pluginPool, plugin)
+	pluginDirs[filepath.Dir(file)] = plugin
+}
+
+// ForEachPlugin iterates through registered plugins.
+func ForEachPlugin(iterator func(info PluginInfo) bool) {
+	pluginLock.RLock()
+	plugins := append([]IPlugin(nil), pluginPool...)
+	pluginLock.RUnlock()
+	for _, plugin := range plugins {
+		if !iterator(plugin.GetPluginInfo()) {
+			return
+		}
+	}
+}
+
+// lookupPlugin 根据源文件查找其所属的插件
+func lookupPlugin(file string) (IPlugin, bool) {
+	if file == "" {
+		return nil, false
+	}
+	pluginLock.RLock()
+	defer pluginLock.RUnlock()
+	plugin, ok := pluginDirs[filepath.Dir(file)]
+	return plugin, ok
 }
@@ -1,9 +1,17 @@
 package zero
 
 import (
+	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // Type check the event's type
@@ -26,7 +34,7 @@ func Type(type_ string) Rule {
 // PrefixRule check if the message has the prefix and trim the prefix
 // PrefixRule 检查消息前缀
 func PrefixRule(prefixes ...string) Rule {
-	return func(event *Event, state State) bool {
+	return describe(func() string { return "PrefixRule(" + quote(prefixes...) + ")" }, func(event *Event, state State) bool {
 		if event.Message == nil || event.Message[0].Type != "text" { // 确保无空指针
 			return false
 		}
@@ -44,13 +52,13 @@ func PrefixRule(prefixes ...string) Rule {
 			}
 		}
 		return false
-	}
+	})
 }
 
 // SuffixRule check if the message has the suffix and trim the suffix
 // SuffixRule 检查消息后缀
 func SuffixRule(suffixes ...string) Rule {
-	return func(event *Event, state State) bool {
+	return describe(func() string { return "SuffixRule(" + quote(suffixes...) + ")" }, func(event *Event, state State) bool {
 		mLen := len(event.Message)
 		if mLen <= 0 { // 确保无空指针
 			return false
@@ -72,47 +80,172 @@ func SuffixRule(suffixes ...string) Rule {
 			}
 		}
 		return false
-	}
+	})
 }
 
 // CommandRule check if the message is a command and trim the command name
+//
+// 消息需要以命令前缀开头, @机器人 或以机器人昵称开头时可以省略命令前缀。
+// 命令只在词边界处匹配且忽略大小写和全角半角的区别, 有多个命令可以匹配时
+// 使用最长的命令, 若其他 Matcher 注册了更长的可匹配命令且该 Matcher 的
+// 其他 Rule 也都满足(如权限, 插件未被禁用), 则交由该 Matcher 处理
 func CommandRule(commands ...string) Rule {
-	return func(event *Event, state State) bool {
-		if event.Message == nil || event.Message[0].Type != "text" {
+	return describe(func() string { return "CommandRule(" + quote(commands...) + ")" }, func(event *Event, state State) bool {
+		command, end, ok := matchCommand(event, commands)
+		if !ok {
 			return false
 		}
-		first := event.Message[0]
-		firstMessage := first.Data["text"]
-		if !strings.HasPrefix(firstMessage, BotConfig.CommandPrefix) {
+		if longest, ok := eligibleCommand(event); ok && longest > end {
 			return false
 		}
-		cmdMessage := firstMessage[len(BotConfig.CommandPrefix):]
-		for _, command := range commands {
-			if strings.HasPrefix(cmdMessage, command) {
-				state["command"] = command
-				arg := strings.TrimLeft(cmdMessage[len(command):], " ")
-				if len(event.Message) > 1 {
-					arg += event.Message[1:].ExtractPlainText()
-				}
-				state["args"] = arg
-				return true
+		state["command"] = command
+		arg := strings.TrimLeftFunc(event.Message[0].Data["text"][end:], unicode.IsSpace)
+		if len(event.Message) > 1 {
+			arg += event.Message[1:].ExtractPlainText()
+		}
+		state["args"] = arg
+		return true
+	})
+}
+
+// LookupCommand 返回消息所匹配的最长的已注册命令
+func LookupCommand(event *Event) (*CommandInfo, bool) {
+	matches := commandMatches(event)
+	if len(matches) == 0 {
+		return nil, false
+	}
+	return matches[0].matcher.Command, true
+}
+
+// commandMatch 消息所匹配的命令
+type commandMatch struct {
+	matcher *Matcher
+	end     int // 命令在第一个文本消息中的结束位置
+	ok      bool
+}
+
+// commandMatches 返回消息所匹配的所有已注册命令, 按命令长度从长到短排列
+func commandMatches(event *Event) []commandMatch {
+	var matches []commandMatch
+	matcherLock.RLock()
+	for _, m := range commandList {
+		if _, end, ok := matchCommand(event, append([]string{m.Command.Name}, m.Command.Aliases...)); ok {
+			matches = append(matches, commandMatch{matcher: m, end: end, ok: true})
+		}
+	}
+	matcherLock.RUnlock()
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].end > matches[j].end
+	})
+	return matches
+}
+
+// eligibleCommand 返回消息所匹配的最长的可触发命令的结束位置, 可触发即该命令
+// 的 Matcher 的所有 Rule 都满足. 结果会缓存在 event 中, 每个事件只查找一次
+func eligibleCommand(event *Event) (int, bool) {
+	if event.command != nil { // 已查找过, 或正在检查某个 Matcher 的 Rule
+		return event.command.end, event.command.ok
+	}
+	event.command = &commandMatch{}
+	for _, c := range commandMatches(event) {
+		if c.matcher.eligible(event) {
+			*event.command = c
+			break
+		}
+	}
+	return event.command.end, event.command.ok
+}
+
+// matchCommand 返回消息匹配到的最长命令以及命令在第一个文本消息中的结束位置
+func matchCommand(event *Event, commands []string) (command string, end int, ok bool) {
+	if len(event.Message) == 0 || event.Message[0].Type != "text" {
+		return
+	}
+	text := event.Message[0].Data["text"]
+	prefixes := CommandPrefixes(event.GroupID)
+	if event.IsToMe {
+		prefixes = append(prefixes[:len(prefixes):len(prefixes)], "")
+	}
+	for _, prefix := range prefixes {
+		n, matched := foldPrefix(text, prefix)
+		if !matched {
+			continue
+		}
+		for _, c := range commands {
+			m, matched := foldPrefix(text[n:], c)
+			if !matched || n+m <= end {
+				continue
+			}
+			if r, _ := utf8.DecodeRuneInString(text[n+m:]); n+m < len(text) && !unicode.IsSpace(r) {
+				continue // 不在词边界
 			}
+			command, end, ok = c, n+m, true
 		}
-		return false
 	}
+	return
+}
+
+// TrimCommandPrefix 忽略大小写和全角半角去除 text 开头的命令前缀,
+// 返回匹配到的最长前缀以及去除前缀后的文本, 前缀规则与 CommandRule 相同
+func TrimCommandPrefix(groupID int64, text string) (prefix, rest string, ok bool) {
+	end := -1
+	for _, p := range CommandPrefixes(groupID) {
+		if n, matched := foldPrefix(text, p); matched && n > end {
+			prefix, end, ok = p, n, true
+		}
+	}
+	if !ok {
+		return "", text, false
+	}
+	return prefix, text[end:], true
+}
+
+// foldPrefix 忽略大小写和全角半角检查 s 是否以 prefix 开头,
+// prefix 中的空白可以匹配任意长度的空白, 返回 prefix 在 s 中对应的长度
+func foldPrefix(s, prefix string) (int, bool) {
+	i := 0
+	for _, p := range prefix {
+		if i >= len(s) {
+			return 0, false
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(p) {
+			if !unicode.IsSpace(r) {
+				return 0, false
+			}
+			i += len(s[i:]) - len(strings.TrimLeftFunc(s[i:], unicode.IsSpace))
+			continue
+		}
+		if foldRune(r) != foldRune(p) {
+			return 0, false
+		}
+		i += size
+	}
+	return i, true
+}
+
+// foldRune 将全角字符转换为半角并转换为小写
+func foldRune(r rune) rune {
+	switch {
+	case r == '\u3000':
+		r = ' '
+	case r >= '\uff01' && r <= '\uff5e':
+		r -= 0xfee0
+	}
+	return unicode.ToLower(r)
 }
 
 // RegexRule check if the message can be matched by the regex pattern
 func RegexRule(regexPattern string) Rule {
 	regex := regexp.MustCompile(regexPattern)
-	return func(event *Event, state State) bool {
+	return describe(func() string { return "RegexRule(" + quote(regexPattern) + ")" }, func(event *Event, state State) bool {
 		msg := event.RawMessage
 		if regex.MatchString(msg) {
 			state["regex_matched"] = regex.FindStringSubmatch(msg)
 			return true
 		}
 		return false
-	}
+	})
 }
 
 // ReplyRule check if the message is replying some message
@@ -131,7 +264,7 @@ func ReplyRule(messageID int64) Rule {
 
 // KeywordRule check if the message has a keyword or keywords
 func KeywordRule(src ...string) Rule {
-	return func(event *Event, state State) bool {
+	return describe(func() string { return "KeywordRule(" + quote(src...) + ")" }, func(event *Event, state State) bool {
 		msg := event.Message.CQString()
 		for _, str := range src {
 			if strings.Contains(msg, str) {
@@ -140,12 +273,12 @@ func KeywordRule(src ...string) Rule {
 			}
 		}
 		return false
-	}
+	})
 }
 
 // FullMatchRule check if src has the same copy of the message
 func FullMatchRule(src ...string) Rule {
-	return func(event *Event, state State) bool {
+	return describe(func() string { return "FullMatchRule(" + quote(src...) + ")" }, func(event *Event, state State) bool {
 		msg := event.Message.CQString()
 		for _, str := range src {
 			if str == msg {
@@ -154,7 +287,7 @@ func FullMatchRule(src ...string) Rule {
 			}
 		}
 		return false
-	}
+	})
 }
 
 // OnlyToMe only triggered in conditions of @bot or begin with the nicknames
@@ -174,6 +307,18 @@ func CheckUser(userId ...int64) Rule {
 	}
 }
 
+// CheckGroup only triggered in specific group
+func CheckGroup(groupId ...int64) Rule {
+	return func(event *Event, state State) bool {
+		for _, gid := range groupId {
+			if event.GroupID == gid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // OnlyPrivate requires that the event is private message
 func OnlyPrivate(event *Event, _ State) bool {
 	return event.PostType == "message" && event.DetailType == "private"
@@ -205,3 +350,129 @@ func OwnerPermission(event *Event, state State) bool {
 	return SuperUserPermission(event, state) ||
 		(event.Sender.Role != "member" && event.Sender.Role != "admin")
 }
+
+// And 当所有 Rule 都满足时满足, 只有全部满足时才会将各 Rule 写入的 State 合并
+func And(rules ...Rule) Rule {
+	return describe(func() string { return "And(" + joinRules(rules) + ")" }, func(event *Event, state State) bool {
+		tmp := copyState(state)
+		for _, rule := range rules {
+			if !rule(event, tmp) {
+				return false
+			}
+		}
+		mergeState(state, tmp)
+		return true
+	})
+}
+
+// Or 当任一 Rule 满足时满足, 只有第一个满足的 Rule 写入的 State 会被合并
+func Or(rules ...Rule) Rule {
+	return describe(func() string { return "Or(" + joinRules(rules) + ")" }, func(event *Event, state State) bool {
+		for _, rule := range rules {
+			tmp := copyState(state)
+			if rule(event, tmp) {
+				mergeState(state, tmp)
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not 当 Rule 不满足时满足, 该 Rule 写入的 State 总是会被丢弃
+func Not(rule Rule) Rule {
+	return describe(func() string { return "Not(" + RuleString(rule) + ")" }, func(event *Event, state State) bool {
+		return !rule(event, copyState(state))
+	})
+}
+
+func mergeState(dst, src State) {
+	if dst == nil {
+		return
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+var (
+	// 已添加描述的 Rule, 同时持有 Rule 防止其被回收后地址被复用
+	ruleDescriptions = sync.Map{}
+	// 是否记录内置 Rule 的描述
+	recordDescriptions int32
+)
+
+type describedRule struct {
+	rule        Rule
+	description string
+}
+
+// RecordRuleDescriptions 设置是否记录之后创建的内置 Rule (如 CommandRule, And) 的描述,
+// 默认不记录, 此时 RuleString 只能返回其函数名. 记录的描述会一直保留, 请只在调试时开启
+func RecordRuleDescriptions(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&recordDescriptions, v)
+}
+
+// Describe 为 Rule 添加描述, 返回的新 Rule 与原 Rule 行为相同
+//
+// 描述会一直保留, 不应对运行中动态创建的大量 Rule 使用
+func Describe(description string, rule Rule) Rule {
+	described := func(event *Event, state State) bool {
+		return rule(event, state)
+	}
+	ruleDescriptions.Store(ruleKey(described), describedRule{rule: described, description: description})
+	return described
+}
+
+// describe 在开启 RecordRuleDescriptions 时为内置的 rule 添加描述, rule 必须是新创建的闭包
+func describe(description func() string, rule Rule) Rule {
+	if atomic.LoadInt32(&recordDescriptions) != 0 {
+		ruleDescriptions.Store(ruleKey(rule), describedRule{rule: rule, description: description()})
+	}
+	return rule
+}
+
+// ruleKey 返回闭包对象的地址, 每个闭包实例的地址都不同
+func ruleKey(rule Rule) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&rule))
+}
+
+// RuleString 返回 Rule 的描述, 没有描述时返回其函数名
+func RuleString(rule Rule) string {
+	if rule == nil {
+		return "<nil>"
+	}
+	if d, ok := ruleDescriptions.Load(ruleKey(rule)); ok {
+		return d.(describedRule).description
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(rule).Pointer()).Name()
+	name = name[strings.LastIndexByte(name, '/')+1:]
+	for { // 去除闭包的 .func1 后缀
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 || !strings.HasPrefix(name[i+1:], "func") {
+			break
+		}
+		name = name[:i]
+	}
+	return name
+}
+
+func joinRules(rules []Rule) string {
+	s := make([]string, len(rules))
+	for i, rule := range rules {
+		s[i] = RuleString(rule)
+	}
+	return strings.Join(s, ", ")
+}
+
+func quote(s ...string) string {
+	q := make([]string, len(s))
+	for i := range s {
+		q[i] = strconv.Quote(s[i])
+	}
+	return strings.Join(q, ", ")
+}
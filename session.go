@@ -0,0 +1,107 @@
+package zero
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSessionTimeout 会话超时
+	ErrSessionTimeout = errors.New("session timeout")
+	// ErrSessionCanceled 会话被用户取消
+	ErrSessionCanceled = errors.New("session canceled")
+)
+
+// SessionConfig 是交互会话的设置
+type SessionConfig struct {
+	Timeout      time.Duration `json:"timeout"`       // 会话超时时间, Receive 等默认为 5 分钟, Get 和 FutureEvent.Next 仅在设置后超时
+	ExitWords    []string      `json:"exit_words"`    // 退出会话的关键词, 默认为 取消 和 /cancel
+	TimeoutReply string        `json:"timeout_reply"` // 会话超时时的回复, 为空时不回复
+	CancelReply  string        `json:"cancel_reply"`  // 会话被取消时的回复, 为空时不回复
+	RetryReply   string        `json:"retry_reply"`   // Reject 或 Pause 达到 Matcher.MaxRetry 时的回复, 为空时不回复
+}
+
+func (c *SessionConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 5 * time.Minute
+}
+
+// context 返回 Get 等待消息使用的 context, 只有设置了 Timeout 时才会超时
+func (c *SessionConfig) context() (context.Context, context.CancelFunc) {
+	if c.Timeout > 0 {
+		return context.WithTimeout(context.Background(), c.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// IsExitWord 检查消息是否为退出会话的关键词
+func (c *SessionConfig) IsExitWord(event *Event) bool {
+	words := c.ExitWords
+	if words == nil {
+		words = []string{"取消", "/cancel"}
+	}
+	text := strings.TrimSpace(event.Message.ExtractPlainText())
+	for _, word := range words {
+		if text == word {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionRule 返回只匹配与 event 同一用户在同一会话(群聊或私聊)中消息的 Rule
+func SessionRule(event *Event) Rule {
+	userRule, groupRule := CheckUser(event.UserID), CheckGroup(event.GroupID)
+	return func(e *Event, state State) bool {
+		return userRule(e, state) && groupRule(e, state)
+	}
+}
+
+// Receive 等待触发当前 Matcher 的用户在同一会话中的下一条消息
+//
+// timeout 小于等于 0 时使用 BotConfig.Session.Timeout, 超时或用户发送退出关键词时,
+// 将回复 BotConfig.Session 中对应的提示并返回 ErrSessionTimeout 或 ErrSessionCanceled
+func (m *Matcher) Receive(timeout time.Duration) (Event, error) {
+	conf := &BotConfig.Session
+	if timeout <= 0 {
+		timeout = conf.timeout()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return m.receive(ctx)
+}
+
+// receive 等待触发者在同一会话中的下一条消息, 直到 ctx 被取消
+func (m *Matcher) receive(ctx context.Context) (Event, error) {
+	conf := &BotConfig.Session
+	e, err := m.FutureEvent("message", SessionRule(m.Event)).NextContext(ctx)
+	if err != nil {
+		if conf.TimeoutReply != "" {
+			Send(*m.Event, conf.TimeoutReply)
+		}
+		return Event{}, ErrSessionTimeout
+	}
+	if conf.IsExitWord(&e) {
+		if conf.CancelReply != "" {
+			Send(*m.Event, conf.CancelReply)
+		}
+		return Event{}, ErrSessionCanceled
+	}
+	return e, nil
+}
+
+// GetWithTimeout 发送 prompt 并返回触发者在同一会话中的下一条消息
+//
+// 超时和取消的处理同 Receive
+func (m *Matcher) GetWithTimeout(prompt string, timeout time.Duration) (string, error) {
+	Send(*m.Event, prompt)
+	e, err := m.Receive(timeout)
+	if err != nil {
+		return "", err
+	}
+	return e.RawMessage, nil
+}
@@ -82,6 +82,8 @@ type Event struct {
 	NativeMessage jsoniter.RawMessage `json:"message"`
 	IsToMe        bool                `json:"-"`
 	RawEvent      gjson.Result        `json:"-"` // raw event
+
+	command *commandMatch // 消息匹配到的命令, 每个事件只查找一次
 }
 
 type Message struct {